@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"gorecTool/internal/diff"
+	"gorecTool/internal/store"
+
+	"github.com/spf13/cobra"
+)
+
+// diffCmd compares two stored scans
+var diffCmd = &cobra.Command{
+	Use:   "diff <old-scan-id> [new-scan-id]",
+	Short: "Show what changed between two scans",
+	Long: `Compares the findings of two stored scans and prints added (+),
+removed (-) and changed (~) subdomains, ports, HTTP services and alerts.
+With a single scan ID, compares it against the previous completed scan of the same target.`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db, err := store.Open(dbPath)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		var oldID, newID string
+		if len(args) == 2 {
+			oldID, newID = args[0], args[1]
+		} else {
+			newID = args[0]
+			scan, err := db.Scan(newID)
+			if err != nil {
+				return fmt.Errorf("%s: %w", newID, err)
+			}
+			prev, err := db.PreviousScan(scan.Target, newID)
+			if err != nil {
+				return fmt.Errorf("no earlier completed scan of %s to compare with", scan.Target)
+			}
+			oldID = prev.ID
+		}
+
+		result, err := diffScans(db, oldID, newID)
+		if err != nil {
+			return err
+		}
+		printDiff(oldID, newID, result)
+		return nil
+	},
+}
+
+func diffScans(db *store.Store, oldID, newID string) (diff.Result, error) {
+	older, err := db.Events(oldID)
+	if err != nil {
+		return diff.Result{}, fmt.Errorf("%s: %w", oldID, err)
+	}
+	newer, err := db.Events(newID)
+	if err != nil {
+		return diff.Result{}, fmt.Errorf("%s: %w", newID, err)
+	}
+	return diff.Compare(older, newer), nil
+}
+
+// printSinceLast diffs a just-finished scan against the previous one
+func printSinceLast(db *store.Store, target, scanID string) {
+	prev, err := db.PreviousScan(target, scanID)
+	if errors.Is(err, store.ErrNotFound) {
+		fmt.Fprintf(humanOut, "[*] No earlier completed scan of %s to compare with.\n", target)
+		return
+	} else if err != nil {
+		fmt.Fprintf(humanOut, "[!] Could not look up the previous scan: %v\n", err)
+		return
+	}
+	result, err := diffScans(db, prev.ID, scanID)
+	if err != nil {
+		fmt.Fprintf(humanOut, "[!] Could not diff against %s: %v\n", prev.ID, err)
+		return
+	}
+	printDiff(prev.ID, scanID, result)
+}
+
+func printDiff(oldID, newID string, r diff.Result) {
+	fmt.Fprintf(humanOut, "\n=== CHANGES %s -> %s ===\n", oldID, newID)
+	if r.Empty() {
+		fmt.Fprintln(humanOut, "No changes.")
+		return
+	}
+	for _, e := range r.Added {
+		fmt.Fprintf(humanOut, "+ [%s] %s %s\n", e.Type, e.Target, e.Payload)
+	}
+	for _, e := range r.Removed {
+		fmt.Fprintf(humanOut, "- [%s] %s %s\n", e.Type, e.Target, e.Payload)
+	}
+	for _, c := range r.Changed {
+		fmt.Fprintf(humanOut, "~ [%s] %s %s\n", c.New.Type, c.New.Target, c.New.Payload)
+		for _, f := range c.Fields {
+			fmt.Fprintf(humanOut, "      %s\n", f)
+		}
+	}
+	fmt.Fprintf(humanOut, "%d added, %d removed, %d changed\n", len(r.Added), len(r.Removed), len(r.Changed))
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+}
@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"gorecTool/internal/output"
+	"gorecTool/internal/store"
+
+	"github.com/spf13/cobra"
+)
+
+var historyOutput string
+
+// historyCmd lists past scans, or shows the findings of one
+var historyCmd = &cobra.Command{
+	Use:   "history [scan-id]",
+	Short: "List past scans or show the findings of one",
+	Long: `Without arguments, lists every scan stored in the findings database.
+With a scan ID, prints every finding recorded during that scan
+(optionally as json, jsonl or csv with --output).`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db, err := store.Open(dbPath)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		if len(args) == 0 {
+			return listScans(db)
+		}
+		return showScan(db, args[0])
+	},
+}
+
+func listScans(db *store.Store) error {
+	scans, err := db.Scans()
+	if err != nil {
+		return err
+	}
+	if len(scans) == 0 {
+		fmt.Println("No scans recorded yet.")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tTARGET\tSTARTED\tSTATUS\tEVENTS")
+	for _, s := range scans {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\n",
+			s.ID, s.Target, s.Started.Local().Format("2006-01-02 15:04:05"), s.Status, s.Events)
+	}
+	return w.Flush()
+}
+
+func showScan(db *store.Store, id string) error {
+	scan, err := db.Scan(id)
+	if err != nil {
+		return fmt.Errorf("%s: %w", id, err)
+	}
+	events, err := db.Events(id)
+	if err != nil {
+		return err
+	}
+	if historyOutput != "" {
+		return output.WriteAll(historyOutput, os.Stdout, events)
+	}
+
+	fmt.Printf("Scan %s on %s (%s, started %s)\n",
+		scan.ID, scan.Target, scan.Status, scan.Started.Local().Format("2006-01-02 15:04:05"))
+	for _, e := range events {
+		fmt.Printf("  %s  %-16s %s %s\n", e.Time.Local().Format("15:04:05"), e.Type, e.Target, e.Payload)
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+	historyCmd.Flags().StringVarP(&historyOutput, "output", "o", "", "Print the scan's findings as json, jsonl or csv")
+}
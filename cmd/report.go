@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"gorecTool/internal/report"
+	"gorecTool/internal/store"
+
+	"github.com/spf13/cobra"
+)
+
+var reportFormat string
+var reportFile string
+
+// reportCmd renders a stored scan as a shareable HTML or Markdown report
+var reportCmd = &cobra.Command{
+	Use:   "report [scan-id]",
+	Short: "Render a stored scan as an HTML or Markdown report",
+	Long: `Builds a self-contained report from a scan in the findings database:
+the subdomain inventory, open ports and web services per host, and the
+alerts grouped by severity. Without a scan ID the most recent scan is used.
+
+Example: gorecon report 20240101-120000-ab12 --format html -o report.html`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db, err := store.Open(dbPath)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		var scan store.Scan
+		if len(args) == 1 {
+			if scan, err = db.Scan(args[0]); err != nil {
+				return fmt.Errorf("%s: %w", args[0], err)
+			}
+		} else {
+			scans, err := db.Scans()
+			if err != nil {
+				return err
+			}
+			if len(scans) == 0 {
+				return fmt.Errorf("no scans recorded yet")
+			}
+			scan = scans[0]
+		}
+		events, err := db.Events(scan.ID)
+		if err != nil {
+			return err
+		}
+
+		r := report.Build(report.Meta{
+			ScanID:   scan.ID,
+			Target:   scan.Target,
+			Started:  scan.Started,
+			Finished: scan.Finished,
+			Status:   scan.Status,
+		}, events)
+
+		out := io.Writer(os.Stdout)
+		if reportFile != "" {
+			f, err := os.Create(reportFile)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			out = f
+		}
+		if err := r.Render(reportFormat, out); err != nil {
+			return err
+		}
+		if reportFile != "" {
+			fmt.Fprintf(os.Stderr, "[*] Report for scan %s written to %s\n", scan.ID, reportFile)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(reportCmd)
+	reportCmd.Flags().StringVarP(&reportFormat, "format", "f", "html", "Report format: html or md")
+	reportCmd.Flags().StringVarP(&reportFile, "output", "o", "", "Write the report to this file instead of stdout")
+}
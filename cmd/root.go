@@ -3,9 +3,14 @@ package cmd
 import (
 	"os"
 
+	"gorecTool/internal/store"
+
 	"github.com/spf13/cobra"
 )
 
+// dbPath is the findings database shared by scan, history, ...
+var dbPath string
+
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:   "gorecon",
@@ -31,3 +36,7 @@ func Execute() {
 	}
 	print("cmd is ending")
 }
+
+func init() {
+	rootCmd.PersistentFlags().StringVar(&dbPath, "db", store.DefaultPath(), "Path of the findings database")
+}
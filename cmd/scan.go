@@ -2,296 +2,550 @@ package cmd
 
 import (
 	"bufio"
+	"context"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
+	"slices"
+	"syscall"
+	"time"
 
 	// Import your internal packages
 	"gorecTool/internal/engine"
 	"gorecTool/internal/modules"
-	"strconv"
+	"gorecTool/internal/output"
+	"gorecTool/internal/ratelimit"
+	"gorecTool/internal/resolver"
+	"gorecTool/internal/rules"
+	"gorecTool/internal/scope"
+	"gorecTool/internal/store"
+	"gorecTool/internal/targets"
 	"strings"
-	"sync"
 
 	"github.com/spf13/cobra"
 )
 
+// maxGroupsInFlight caps how many addresses are port scanned at once
+const maxGroupsInFlight = 16
+
 // Variables to store flag values
 var targetDomain string
 var isDeepScan bool
+var scanTimeout time.Duration
+var rulesFile string
+var sinceLast bool
+var outputFormat string
+var outputFile string
+var includePatterns []string
+var excludePatterns []string
+var deepAll bool
+var deepTargetsFile string
+var wordlistFile string
+var permutations bool
+var resolverSpecs []string
+var resolversFile string
+var dnsRate float64
+var takeoverSignatures string
+var portSpec string
+var topPorts int
+var scanRate float64
+var portStates bool
+var udpScan bool
+var udpPortSpec string
+var ipFamily string
+var cidrSpecs []string
+var targetsFile string
+var reverseDNS bool
+var scopeFile string
+
+// Compiled forms of the target selection flags
+var includeFilter, excludeFilter, deepTargets *targetFilter
+
+// humanOut receives the human-oriented progress text. It moves to stderr
+// when --output streams machine-readable results to stdout.
+var humanOut io.Writer = os.Stdout
 
 // scanCmd represents the scan command
 var scanCmd = &cobra.Command{
 	Use:   "scan",
 	Short: "Start a reconnaissance scan on a target",
-	Long:  `Initiates the autonomous scanning engine on a specific domain.`,
+	Long: `Initiates the autonomous scanning engine on a specific domain, or on a
+list of domains, IP addresses, CIDR blocks and IP ranges (--cidr,
+--targets-file, or piped into stdin). Raw addresses skip subdomain discovery.`,
 
 	// Example: ./gorecon scan -d example.com
+	//          ./gorecon scan --cidr 10.0.0.0/24 --ptr
+	//          cat hosts.txt | ./gorecon scan
 	Run: func(cmd *cobra.Command, args []string) {
 		// 1. Setup Engine (Still needed for logging/logic)
 
-		if targetDomain == "" {
-			fmt.Println("Error: You must provide a domain using the -d flag.")
+		// Machine-readable output (--output), streamed as the engine goes
+		var results output.Writer
+		if outputFormat != "" {
+			dest := io.Writer(os.Stdout)
+			if outputFile != "" {
+				f, err := os.Create(outputFile)
+				if err != nil {
+					fmt.Fprintf(humanOut, "Error: --output-file: %v\n", err)
+					return
+				}
+				defer f.Close()
+				dest = f
+			} else {
+				humanOut = os.Stderr
+			}
+			var err error
+			if results, err = output.New(outputFormat, dest); err != nil {
+				fmt.Fprintf(humanOut, "Error: --output: %v\n", err)
+				return
+			}
+		}
+
+		// Targets: -d, --cidr, --targets-file, or whatever is piped in
+		var err error
+		var inputs []string
+		if targetDomain != "" {
+			inputs = append(inputs, targetDomain)
+		}
+		inputs = append(inputs, cidrSpecs...)
+		scanLabel := targetDomain
+		if scanLabel == "" && len(cidrSpecs) > 0 {
+			scanLabel = strings.Join(cidrSpecs, ",")
+		}
+		switch {
+		case targetsFile != "":
+			specs, err := targets.Load(targetsFile)
+			if err != nil {
+				fmt.Fprintf(humanOut, "Error: --targets-file: %v\n", err)
+				return
+			}
+			inputs = append(inputs, specs...)
+			if scanLabel == "" && targetsFile != "-" {
+				scanLabel = targetsFile
+			}
+		case len(inputs) == 0 && !stdinIsTerminal():
+			if inputs, err = targets.Read(os.Stdin); err != nil {
+				fmt.Fprintf(humanOut, "Error: reading targets from stdin: %v\n", err)
+				return
+			}
+		}
+		if scanLabel == "" {
+			scanLabel = "stdin"
+		}
+		scanList, err := targets.Parse(inputs)
+		if err != nil {
+			fmt.Fprintf(humanOut, "Error: %v\n", err)
+			return
+		}
+		if scanList.Len() == 0 {
+			fmt.Fprintln(humanOut, "Error: You must provide a domain using the -d flag, or targets with --cidr, --targets-file or stdin.")
+			return
+		}
+		if includeFilter, err = newTargetFilter(includePatterns); err != nil {
+			fmt.Fprintf(humanOut, "Error: --include: %v\n", err)
+			return
+		}
+		if excludeFilter, err = newTargetFilter(excludePatterns); err != nil {
+			fmt.Fprintf(humanOut, "Error: --exclude: %v\n", err)
+			return
+		}
+		if deepTargetsFile != "" {
+			if deepTargets, err = loadTargetPatterns(deepTargetsFile); err != nil {
+				fmt.Fprintf(humanOut, "Error: --deep-targets: %v\n", err)
+				return
+			}
+		}
+		var authorized *scope.Scope
+		if scopeFile != "" {
+			if authorized, err = scope.Load(scopeFile); err != nil {
+				fmt.Fprintf(humanOut, "Error: --scope: %v\n", err)
+				return
+			}
+			fmt.Fprintf(humanOut, "[*] Scope: %s (%s)\n", scopeFile, authorized)
+		}
+		var wordlist []string
+		if wordlistFile != "" {
+			if wordlist, err = modules.LoadWordlist(wordlistFile); err != nil {
+				fmt.Fprintf(humanOut, "Error: --wordlist: %v\n", err)
+				return
+			}
+			fmt.Fprintf(humanOut, "[*] Loaded %d words from %s\n", len(wordlist), wordlistFile)
+		}
+		var dnsPool *resolver.Pool
+		if resolversFile != "" {
+			specs, err := resolver.LoadFile(resolversFile)
+			if err != nil {
+				fmt.Fprintf(humanOut, "Error: --resolvers-file: %v\n", err)
+				return
+			}
+			resolverSpecs = append(resolverSpecs, specs...)
+		}
+		if len(resolverSpecs) > 0 {
+			servers, err := resolver.Parse(resolverSpecs)
+			if err != nil {
+				fmt.Fprintf(humanOut, "Error: --resolvers: %v\n", err)
+				return
+			}
+			dnsPool = resolver.NewPool(servers, dnsRate)
+			fmt.Fprintf(humanOut, "[*] Resolving through %d DNS servers (%g queries/s each)\n", dnsPool.Len(), dnsRate)
+		}
+		var signatures []modules.TakeoverSignature
+		if takeoverSignatures != "" {
+			if signatures, err = modules.LoadSignatures(takeoverSignatures); err != nil {
+				fmt.Fprintf(humanOut, "Error: --takeover-signatures: %v\n", err)
+				return
+			}
+		}
+		// Picking deep targets up front implies a deep scan
+		if deepAll || deepTargets != nil {
+			isDeepScan = true
+		}
+		// --ports / --top-ports replace the Top 20 list of quick scans
+		var quickPorts []int
+		if portSpec != "" {
+			if quickPorts, err = modules.ParsePorts(portSpec); err != nil {
+				fmt.Fprintf(humanOut, "Error: --ports: %v\n", err)
+				return
+			}
+		}
+		if topPorts > modules.MaxTopPorts {
+			fmt.Fprintf(humanOut, "Error: --top-ports: the bundled ranking has %d ports, use --ports for more\n", modules.MaxTopPorts)
 			return
 		}
+		if topPorts > 0 {
+			quickPorts = modules.MergePorts(quickPorts, modules.TopPorts(topPorts))
+		}
+		if !slices.Contains(modules.Families, ipFamily) {
+			fmt.Fprintf(humanOut, "Error: --ip-family must be one of %s\n", strings.Join(modules.Families, ", "))
+			return
+		}
+		// --udp-ports implies --udp
+		var udpPorts []int
+		if udpPortSpec != "" {
+			if udpPorts, err = modules.ParsePorts(udpPortSpec); err != nil {
+				fmt.Fprintf(humanOut, "Error: --udp-ports: %v\n", err)
+				return
+			}
+			udpScan = true
+		}
 		if isDeepScan {
-			fmt.Println("[*] Mode: DEEP SCAN (This will take longer requires user input)")
+			fmt.Fprintln(humanOut, "[*] Mode: DEEP SCAN (This will take longer)")
+		} else if quickPorts != nil {
+			fmt.Fprintf(humanOut, "[*] Mode: QUICK SCAN (%d ports)\n", len(quickPorts))
 		} else {
-			fmt.Println("[*] Mode: QUICK SCAN (Top 20 ports only)")
+			fmt.Fprintln(humanOut, "[*] Mode: QUICK SCAN (Top 20 ports only)")
+		}
+		if udpScan {
+			n := len(udpPorts)
+			if n == 0 {
+				n = len(modules.DefaultUDPPorts)
+			}
+			fmt.Fprintf(humanOut, "[*] UDP scan enabled (%d ports)\n", n)
+		}
+		ruleSpecs := rules.Default()
+		if rulesFile != "" {
+			if ruleSpecs, err = rules.Load(rulesFile); err != nil {
+				fmt.Fprintf(humanOut, "Error: could not load rules: %v\n", err)
+				return
+			}
+			fmt.Fprintf(humanOut, "[*] Loaded %d rules from %s\n", len(ruleSpecs), rulesFile)
 		}
-		var engineWg sync.WaitGroup
-
-		brain := engine.NewEngine(&engineWg)
 
-		var analysisWg sync.WaitGroup
-		// 2. Setup Modules
-		subEnum := modules.NewSubdomainModule(brain)
-		portScanner := modules.NewPortScanner(brain)
-		httpAnalyzer := modules.NewHttpAnalyzer(brain)
-		fileHunter := modules.NewFileHunter(brain)
-		portScanner.ScanTarget(targetDomain, isDeepScan)
-		var scanWg sync.WaitGroup
-		// 3. Add Rules (Ideally, move these to a separate 'rules' package later)
-		// brain.AddRule(engine.Rule{
-		// 	Name:      "Auto-Scan-Subdomain",
-		// 	Condition: func(e engine.Event) bool { return e.Type == engine.EventSubdomainFound },
-		// 	Action: func(e engine.Event) {
-		// 		scanWg.Add(1)
-		// 		go func() {
-		// 			defer scanWg.Done()
-		// 			portScanner.ScanTarget(e.Target, isDeepScan)
-		// 		}()
-		// 	},
-		// })
-		brain.AddRule(engine.Rule{
-			Name: "Context-Fuzzer",
-			Condition: func(e engine.Event) bool {
-				// Only trigger if we successfully analyzed the HTTP service
-				return e.Type == engine.EventHttpService
-			},
-			Action: func(e engine.Event) {
-				// The payload format from HttpAnalyzer is: "ServerHeader|TechStack"
-				parts := strings.Split(e.Payload, "|")
-				if len(parts) < 3 {
-					return
-				} // Safety check
+		// Ctrl-C (or --timeout) cancels everything; we still print what we found
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		// After the first Ctrl-C, give the signals back to the default
+		// handler: a second one kills a shutdown that hangs
+		go func() {
+			<-ctx.Done()
+			stop()
+		}()
+		if scanTimeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, scanTimeout)
+			defer cancel()
+		}
 
-				techStack := parts[1]
+		// A DNS server that answers for any name would make every guess alive
+		if dnsPool != nil {
+			for _, r := range dnsPool.Verify(ctx) {
+				fmt.Fprintf(humanOut, "[!] Dropping DNS server %s: it answers for names that don't exist\n", r)
+			}
+			if dnsPool.Len() == 0 {
+				fmt.Fprintln(humanOut, "Error: --resolvers: every DNS server answers for names that don't exist")
+				return
+			}
+		}
 
-				port, _ := strconv.Atoi(parts[2]) // Extract the port we just added
+		brain := engine.NewEngine(ctx)
+		if authorized != nil {
+			brain.SetScope(authorized)
+		}
+		brain.AddObserver(&engine.ConsoleObserver{Out: humanOut})
+		if results != nil {
+			brain.AddObserver(results)
+		}
+		findings := engine.NewCollector()
+		brain.AddObserver(findings)
 
-				analysisWg.Add(1)
-				go func() {
-					defer analysisWg.Done()
-					fileHunter.Hunt(e.Target, port, techStack)
-				}()
-				// We need the port. Since the 'Target' in HttpService event is usually just the domain/IP,
-				// we might need to assume the port or include it in the event.
-				// Ideally, update HttpAnalyzer to include port in Target string (e.g., "example.com:80")
-				// For now, let's assume port 80 if not specified, or extract from target if present.
+		// Record everything into the findings database (best effort)
+		var recorder *store.Recorder
+		if db, err := store.Open(dbPath); err != nil {
+			fmt.Fprintf(humanOut, "[!] Findings will not be saved: %v\n", err)
+		} else {
+			defer db.Close()
+			if scan, err := db.CreateScan(scanLabel); err != nil {
+				fmt.Fprintf(humanOut, "[!] Findings will not be saved: %v\n", err)
+			} else {
+				fmt.Fprintf(humanOut, "[*] Scan ID: %s\n", scan.ID)
+				recorder = store.NewRecorder(db, scan.ID)
+				brain.AddObserver(recorder)
+			}
+		}
 
-				// Simpler Hack: Let's assume standard ports for this demo rule,
-				// or pass the port in the Payload like "Server|Tech|Port"
+		// 2. Setup Modules
+		mods := modules.NewSet(brain)
+		subEnum := mods.Subdomains
+		subEnum.Wordlist = wordlist
+		subEnum.Permutations = permutations
+		if dnsPool != nil {
+			subEnum.Resolver = dnsPool
+			mods.Ports.Resolver = dnsPool
+			mods.Takeover.Resolver = dnsPool
+		}
+		if signatures != nil {
+			mods.Takeover.Signatures = signatures
+		}
+		portScanner := mods.Ports
+		portScanner.Ports = quickPorts
+		portScanner.UDPPorts = udpPorts
+		portScanner.Family = ipFamily
+		portScanner.ReportStates = portStates
+		// One socket budget for every scan in flight (root, groups and the
+		// hosts rules add later), like the GUI: per-scan semaphores add up
+		// to thousands of sockets on a /24
+		portScanner.Limiter = make(chan struct{}, 1000)
+		if scanRate > 0 {
+			// Small bursts keep the pace smooth
+			portScanner.RateLimit = ratelimit.New(scanRate, int(scanRate/20)+1)
+		}
 
-				// Let's stick to the current architecture:
-				// The 'Target' for HttpService event *should* match the input target.
-				// But we need the port. Let's fix this in Step 3 below.
+		// 3. Add Rules (built-in defaults, or the --rules file)
+		// Actions run synchronously: the engine already gives each one its own
+		// goroutine and keeps counting it until it returns.
+		ruleSet, err := rules.Build(ruleSpecs, mods.Actions())
+		if err != nil {
+			fmt.Fprintf(humanOut, "Error: %v\n", err)
+			return
+		}
+		for _, r := range ruleSet {
+			brain.AddRule(r)
+		}
 
-				// Assuming we fixed the data flow:
+		go brain.Start()
+		// Whatever happens below, shut the engine down and report
+		defer func() {
+			brain.Wait()
+			if results != nil {
+				if err := results.Close(); err != nil {
+					fmt.Fprintf(humanOut, "[!] Could not write %s output: %v\n", outputFormat, err)
+				}
+			}
+			printSummary(findings.Events(), ctx.Err())
+			if recorder != nil {
+				finishRecording(recorder, ctx.Err())
+				if sinceLast && ctx.Err() == nil {
+					printSinceLast(recorder.Store, scanLabel, recorder.ScanID)
+				}
+			}
+		}()
+
+		// Root domains are port scanned with everything else in phase 3
+		// (deep with --deep), so an address they share with a subdomain is
+		// scanned once
+		var roots []string
+		for _, domain := range scanList.Domains {
+			if authorized == nil || authorized.InScope(domain) {
+				roots = append(roots, domain)
+			}
+		}
+		var aliveSubdomains []string
+		for _, domain := range scanList.Domains {
+			// 3. PHASE 1: Subdomain Enumeration
+			fmt.Fprintf(humanOut, "\n=== PHASE 1: Enumerating Subdomains of %s ===\n", domain)
+			aliveSubdomains = append(aliveSubdomains, subEnum.Run(ctx, domain)...)
+			if ctx.Err() != nil {
+				return
+			}
+		}
 
-			},
-		})
-		brain.AddRule(engine.Rule{
-			Name: "Web-Discovery",
-			Condition: func(e engine.Event) bool {
-				return e.Type == engine.EventPortOpen &&
-					(e.Payload == "80" || e.Payload == "443" || e.Payload == "8080" || e.Payload == "8443")
-			},
-			Action: func(e engine.Event) {
-				fmt.Printf("    >>> [REPORT] Web Server Found on %s\n", e.Target)
-				// Convert payload (port string) to int
-				port, _ := strconv.Atoi(e.Payload)
-
-				// CHANGE 2: Track the background task!
-				// We increment BEFORE the goroutine starts to ensure we don't exit early
-				analysisWg.Add(1)
-
-				go func() {
-					// Ensure we decrement when the analysis is done
-					defer analysisWg.Done()
-					httpAnalyzer.Analyze(e.Target, port)
-				}()
-			},
-		})
+		// Raw addresses skip discovery; --ptr names them
+		var labels map[string]string
+		if len(scanList.IPs) > 0 {
+			fmt.Fprintf(humanOut, "\n[*] %d IP addresses to scan (no subdomain discovery)\n", len(scanList.IPs))
+			if reverseDNS {
+				labels = subEnum.LabelAddresses(ctx, scanList.IPs)
+				fmt.Fprintf(humanOut, "[*] %d of them have a PTR record\n", len(labels))
+			}
+		}
+		found := append(aliveSubdomains, scanList.IPs...)
+		if ctx.Err() != nil {
+			return
+		}
+		if len(found) == 0 && len(roots) == 0 {
+			fmt.Fprintln(humanOut, "[-] No subdomains found. Exiting.")
+			return
+		}
 
-		engineWg.Add(1)
-		go brain.Start()
-		// 3. PHASE 1: Subdomain Enumeration
-		fmt.Println("\n=== PHASE 1: Enumerating Subdomains ===")
-		aliveSubdomains := subEnum.Run(targetDomain)
+		// Out-of-scope hosts (from passive sources, or a typo in the
+		// target list) are reported but never scanned
+		if authorized != nil {
+			var inScope []string
+			for _, t := range found {
+				if authorized.InScope(t) {
+					inScope = append(inScope, t)
+				}
+			}
+			if n := len(found) - len(inScope); n > 0 {
+				fmt.Fprintf(humanOut, "[Scope] Skipping %d out-of-scope targets\n", n)
+			}
+			found = inScope
+			if len(found) == 0 && len(roots) == 0 {
+				fmt.Fprintln(humanOut, "[-] No targets in scope. Exiting.")
+				return
+			}
+		}
 
-		if len(aliveSubdomains) == 0 {
-			fmt.Println("[-] No subdomains found. Exiting.")
+		// --include / --exclude narrow down what gets scanned at all
+		candidates := filterTargets(found, includeFilter, excludeFilter)
+		if len(candidates) == 0 && len(roots) == 0 {
+			fmt.Fprintf(humanOut, "[-] All %d targets were filtered out by --include/--exclude. Exiting.\n", len(found))
 			return
 		}
 
-		// 4. INTERACTIVE PHASE: Ask the User
-		fmt.Println("\n=== PHASE 2: Target Selection ===")
-		fmt.Println("Found the following live subdomains:")
-		for i, sub := range aliveSubdomains {
-			fmt.Printf("[%d] %s\n", i+1, sub)
+		// 4. TARGET SELECTION: flags first, then ask the user if someone is there
+		fmt.Fprintln(humanOut, "\n=== PHASE 2: Target Selection ===")
+		if len(candidates) > 0 {
+			fmt.Fprintln(humanOut, "Found the following live targets:")
 		}
-		var choice string
-		if isDeepScan {
-			fmt.Println("\nSelect options:")
-			fmt.Println("  'a'      -> Deep Scan ALL (Caution!)")
-			fmt.Println("  '1,3,5'  -> Deep Scan specific numbers")
-			fmt.Println("  'enter'  -> Quick Scan ALL (Default)")
-
-			fmt.Print("\nYour Choice: ")
-			reader := bufio.NewReader(os.Stdin)
-			choice, _ = reader.ReadString('\n')
-			choice = strings.TrimSpace(choice)
-		} else {
-			choice = ""
+		for i, t := range candidates {
+			if label, ok := labels[t]; ok {
+				fmt.Fprintf(humanOut, "[%d] %s (%s)\n", i+1, t, label)
+				continue
+			}
+			fmt.Fprintf(humanOut, "[%d] %s\n", i+1, t)
 		}
 
 		// 5. PHASE 3: Execution
 		var targetsToDeepScan []string
 		var targetsToQuickScan []string
 
-		// Logic to parse user input
-		if choice == "a" {
-			targetsToDeepScan = aliveSubdomains
-		} else if choice == "" {
-			targetsToQuickScan = aliveSubdomains
-		} else {
-			// Parse "1,3,5"
-			indices := strings.Split(choice, ",")
-			selectedMap := make(map[string]bool)
-
-			for _, idx := range indices {
-				i, err := strconv.Atoi(strings.TrimSpace(idx))
-				if err == nil && i > 0 && i <= len(aliveSubdomains) {
-					target := aliveSubdomains[i-1]
-					targetsToDeepScan = append(targetsToDeepScan, target)
-					selectedMap[target] = true
-				}
+		switch {
+		case len(candidates) == 0:
+			fmt.Fprintln(humanOut, "[*] Nothing to select, scanning the root domains only")
+		case deepAll:
+			targetsToDeepScan = candidates
+		case deepTargets != nil:
+			targetsToDeepScan, targetsToQuickScan = splitTargets(candidates, deepTargets)
+			fmt.Fprintf(humanOut, "[*] %d targets selected for deep scan by %s\n", len(targetsToDeepScan), deepTargetsFile)
+		case isDeepScan && stdinIsTerminal():
+			fmt.Fprintln(humanOut, "\nSelect options:")
+			fmt.Fprintln(humanOut, "  'a'      -> Deep Scan ALL (Caution!)")
+			fmt.Fprintln(humanOut, "  '1,3,5'  -> Deep Scan specific numbers")
+			fmt.Fprintln(humanOut, "  'enter'  -> Quick Scan ALL (Default)")
+
+			fmt.Fprint(humanOut, "\nYour Choice: ")
+			choice, ok := readLine(ctx)
+			if !ok {
+				return
 			}
+			targetsToDeepScan, targetsToQuickScan = parseChoice(strings.TrimSpace(choice), candidates)
+		case isDeepScan:
+			// Nobody to ask (cron, CI, a pipe): fall back to the default answer
+			fmt.Fprintln(humanOut, "[*] stdin is not a terminal, quick scanning all targets (use --deep-all or --deep-targets to deep scan without prompting)")
+			targetsToQuickScan = candidates
+		default:
+			targetsToQuickScan = candidates
+		}
 
-			// Add the rest to Quick Scan (Optional, or just ignore them)
-			for _, sub := range aliveSubdomains {
-				if !selectedMap[sub] {
-					targetsToQuickScan = append(targetsToQuickScan, sub)
+		// 6. Launch Scans (tracked by the engine, so Wait covers them too).
+		// Hosts sharing an IP are scanned once; a group gets the deep scan if
+		// any of its hosts was picked for one.
+		deepSet := make(map[string]bool)
+		for _, t := range targetsToDeepScan {
+			deepSet[t] = true
+		}
+		var hosts []string
+		seen := make(map[string]bool)
+		for _, list := range [][]string{roots, targetsToDeepScan, targetsToQuickScan} {
+			for _, h := range list {
+				if !seen[h] {
+					seen[h] = true
+					hosts = append(hosts, h)
 				}
 			}
 		}
+		if isDeepScan {
+			for _, r := range roots {
+				deepSet[r] = true
+			}
+		}
+		groups := portScanner.Plan(ctx, hosts)
+		if n := len(hosts); len(groups) < n {
+			fmt.Fprintf(humanOut, "[*] %d targets share %d unique addresses, scanning each address once\n", n, len(groups))
+		}
+		// Only maxGroupsInFlight addresses are scanned at once, the rest wait
+		// for a slot instead of all queueing on the socket budget
+		slots := make(chan struct{}, maxGroupsInFlight)
+		brain.Go(func() {
+			for _, g := range groups {
+				group := g
+				var ports []int // nil = the quick list
+				for _, h := range group.Hosts {
+					if deepSet[h] {
+						ports = modules.AllPorts()
+						break
+					}
+				}
+				select {
+				case slots <- struct{}{}:
+				case <-ctx.Done():
+					return
+				}
+				brain.Go(func() {
+					defer func() { <-slots }()
+					portScanner.ScanGroup(ctx, group, ports)
+					if udpScan {
+						// Deep scans stay on the UDP list: a silent UDP port costs
+						// two full timeouts, 65k of them would take hours
+						portScanner.ScanGroupUDP(ctx, group, nil)
+					}
+				})
+			}
+		})
 
-		// 6. Launch Scans
-
-		// Launch Deep Scans
-		for _, t := range targetsToDeepScan {
-			scanWg.Add(1)
-			go func(target string) {
-				defer scanWg.Done()
-				// Run Deep Scan (True)
-				portScanner.ScanTarget(target, true)
-			}(t)
-		}
-
-		// Launch Quick Scans (Concurrent with Deep Scans? Maybe limit this)
-		for _, t := range targetsToQuickScan {
-			scanWg.Add(1)
-			go func(target string) {
-				defer scanWg.Done()
-				// Run Quick Scan (False)
-				portScanner.ScanTarget(target, false)
-			}(t)
-		}
-
-		fmt.Println("\n=== PHASE 3: Scanning Started (Please Wait) ===")
-		scanWg.Wait() // Wait for all scans to finish
-		analysisWg.Wait()
-		// Shutdown
-		close(brain.Bus)
-
-		engineWg.Wait()
-
-		fmt.Println("[*] All operations complete.")
-
-		// fmt.Printf("[*] Initializing Engine for Target: %s\n", targetDomain)
-		// // --- THIS IS WHERE WE CONNECT YOUR LOGIC ---\
-
-		// var engineWg sync.WaitGroup
-		// engineWg.Add(1)
-
-		// // 2. The WaitGroup for the SCANNERS (The "Arms & Legs")
-		// var scannerWg sync.WaitGroup
-
-		// // 1. Start the Brain
-		// brain := engine.NewEngine(&engineWg)
-
-		// // 2. Initialize Modules
-		// subEnum := modules.NewSubdomainModule(brain)
-		// portScanner := modules.NewPortScanner(brain)
-
-		// fmt.Println(reflect.TypeOf(brain), reflect.TypeOf(portScanner))
-
-		// // 3. Add Rules (Ideally, move these to a separate 'rules' package later)
-		// brain.AddRule(engine.Rule{
-		// 	Name:      "Auto-Scan-Subdomain",
-		// 	Condition: func(e engine.Event) bool { return e.Type == engine.EventSubdomainFound },
-		// 	Action: func(e engine.Event) {
-		// 		scannerWg.Add(1)
-		// 		go func() {
-		// 			defer scannerWg.Done()
-		// 			portScanner.ScanTarget(e.Target, isDeepScan)
-		// 		}()
-		// 	},
-		// })
-
-		// brain.AddRule(engine.Rule{
-		// 	Name: "Web-Discovery",
-		// 	Condition: func(e engine.Event) bool {
-		// 		return e.Type == engine.EventPortOpen && (e.Payload == "80" || e.Payload == "443")
-		// 	},
-		// 	Action: func(e engine.Event) {
-		// 		fmt.Printf("    >>> [REPORT] Web Server Found on %s\n", e.Target)
-		// 	},
-		// })
-
-		// // 4. Ignite
-		// // Start the Brain
-		// go brain.Start()
-
-		// // --- Start the Initial Job ---
-
-		// fmt.Printf("[*] Starting Scan on %s...\n", targetDomain)
-
-		// // Add 1 for the Subdomain Module (The Trigger)
-		// scannerWg.Add(1)
-		// go func() {
-		// 	defer scannerWg.Done()
-		// 	subEnum.Run(targetDomain)
-		// }()
-
-		// // --- The Graceful Shutdown Logic ---
-
-		// // Block here until all scanners (Subdomain + PortScans) are done.
-		// // As new events come in, scannerWg counter goes up and down.
-		// scannerWg.Wait()
-		// fmt.Println("[*] All scans finished. Shutting down Engine...")
-
-		// // Now that no one is publishing events, close the bus.
-		// // This causes the "range brain.Bus" loop in engine.go to break.
-		// close(brain.Bus)
-
-		// // Wait for the Engine to process remaining events and exit
-		// engineWg.Wait()
-
-		// fmt.Println("[*] Scan Complete. Goodbye.")
+		fmt.Fprintln(humanOut, "\n=== PHASE 3: Scanning Started (Please Wait) ===")
+		// The deferred brain.Wait() blocks until every scan, every queued event
+		// and every rule Action (however deep the chain) has finished.
 	},
 }
 
+// readLine reads one line from stdin, giving up if ctx is cancelled first
+func readLine(ctx context.Context) (string, bool) {
+	lines := make(chan string, 1)
+	go func() {
+		line, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		lines <- line
+	}()
+	select {
+	case line := <-lines:
+		return line, true
+	case <-ctx.Done():
+		return "", false
+	}
+}
+
 func init() {
 	// Register 'scan' as a sub-command of 'root'
 	print("init")
@@ -300,7 +554,48 @@ func init() {
 	// Define flags
 	// func VarP(p *Type, name, shorthand, usage, default)
 	scanCmd.Flags().StringVarP(&targetDomain, "domain", "d", "", "The target domain to scan (e.g., example.com)")
+	scanCmd.Flags().StringSliceVar(&cidrSpecs, "cidr", nil, "Scan these CIDR blocks or IP ranges, e.g. 10.0.0.0/24,192.168.1.10-50 (no subdomain discovery)")
+	scanCmd.Flags().StringVar(&targetsFile, "targets-file", "", "File of domains, IPs, CIDR blocks and ranges to scan, one per line (- for stdin)")
+	scanCmd.Flags().StringVar(&scopeFile, "scope", "", "YAML scope file (include/exclude lists of domains, IPs and CIDRs); nothing outside it is touched")
+	scanCmd.Flags().BoolVar(&reverseDNS, "ptr", false, "Look up PTR records to label raw IP targets")
 	scanCmd.Flags().BoolVar(&isDeepScan, "deep", false, "Enable deep scanning (all ports, brute-force)")
-	// Mark the flag as required if you want to force it
-	scanCmd.MarkFlagRequired("domain")
+	scanCmd.Flags().StringVar(&portSpec, "ports", "", "Ports for quick scans: numbers, ranges and profiles (web, database, remote-admin), e.g. 22,80,8000-9000")
+	scanCmd.Flags().Float64Var(&scanRate, "rate", 0, "Max port probes per second across all targets (0 = unlimited)")
+	scanCmd.Flags().BoolVar(&portStates, "port-states", false, "Also record closed and filtered ports (PORT_STATE events), not just open ones")
+	scanCmd.Flags().BoolVar(&udpScan, "udp", false, "Also scan common UDP services (DNS, NTP, SNMP, IKE, ...) with protocol probes")
+	scanCmd.Flags().StringVar(&udpPortSpec, "udp-ports", "", "UDP ports to scan instead of the built-in list (implies --udp)")
+	scanCmd.Flags().StringVar(&ipFamily, "ip-family", modules.FamilyV4, "Address family to scan: v4, v6 or both (findings are kept per family)")
+	scanCmd.Flags().IntVar(&topPorts, "top-ports", 0, fmt.Sprintf("Quick scan the N most common ports, at most %d (added to --ports)", modules.MaxTopPorts))
+	scanCmd.Flags().StringSliceVar(&includePatterns, "include", nil, "Only scan subdomains matching these globs (or re:<regex>)")
+	scanCmd.Flags().StringSliceVar(&excludePatterns, "exclude", nil, "Skip subdomains matching these globs (or re:<regex>)")
+	scanCmd.Flags().BoolVar(&deepAll, "deep-all", false, "Deep scan every selected subdomain without prompting")
+	scanCmd.Flags().StringVar(&deepTargetsFile, "deep-targets", "", "File of subdomains or patterns to deep scan (the rest get a quick scan), no prompt")
+	scanCmd.Flags().StringVar(&wordlistFile, "wordlist", "", "Brute force subdomains with this wordlist (one label per line)")
+	scanCmd.Flags().BoolVar(&permutations, "permutations", false, "Also try permutations of discovered subdomains (dev-api, api2, ...)")
+	scanCmd.Flags().StringSliceVar(&resolverSpecs, "resolvers", nil, "DNS servers to resolve through, round-robin (e.g. 1.1.1.1,8.8.8.8:53 or system)")
+	scanCmd.Flags().StringVar(&resolversFile, "resolvers-file", "", "File of DNS servers, one per line (added to --resolvers)")
+	scanCmd.Flags().Float64Var(&dnsRate, "dns-rate", 10, "Queries per second sent to each --resolvers server (0 = unlimited)")
+	scanCmd.Flags().StringVar(&takeoverSignatures, "takeover-signatures", "", "JSON takeover signature database to use instead of the bundled one")
+	scanCmd.Flags().StringVar(&rulesFile, "rules", "", "YAML rule file to load instead of the built-in rules")
+	scanCmd.Flags().BoolVar(&sinceLast, "since-last", false, "After the scan, show what changed since the previous scan of this domain")
+	scanCmd.Flags().StringVarP(&outputFormat, "output", "o", "", "Machine-readable results: json, jsonl (streamed) or csv")
+	scanCmd.Flags().StringVar(&outputFile, "output-file", "", "Write --output results to this file instead of stdout")
+	scanCmd.Flags().DurationVar(&scanTimeout, "timeout", 0, "Overall scan deadline, e.g. 30m (0 = no limit)")
+	// -d is optional: --cidr, --targets-file or stdin can provide the targets
+}
+
+// finishRecording closes the scan in the database with the right status
+func finishRecording(r *store.Recorder, scanErr error) {
+	status := store.StatusComplete
+	if scanErr != nil {
+		status = store.StatusInterrupted
+	}
+	if err := r.Close(); err != nil {
+		fmt.Fprintf(humanOut, "[!] Some findings could not be saved: %v\n", err)
+	}
+	if err := r.Store.FinishScan(r.ScanID, status); err != nil {
+		fmt.Fprintf(humanOut, "[!] Could not finalize scan %s: %v\n", r.ScanID, err)
+		return
+	}
+	fmt.Fprintf(humanOut, "[*] Findings saved as scan %s (see `gorecon history %s`)\n", r.ScanID, r.ScanID)
 }
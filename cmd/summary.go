@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"gorecTool/internal/engine"
+)
+
+// printSummary prints the findings of a (possibly interrupted) scan
+func printSummary(events []engine.Event, scanErr error) {
+	fmt.Fprintln(humanOut, "\n=== SUMMARY ===")
+	switch {
+	case errors.Is(scanErr, context.Canceled):
+		fmt.Fprintln(humanOut, "[!] Scan interrupted. Results below are partial.")
+	case errors.Is(scanErr, context.DeadlineExceeded):
+		fmt.Fprintln(humanOut, "[!] Scan deadline reached. Results below are partial.")
+	}
+
+	counts := make(map[engine.EventType]int)
+	for _, e := range events {
+		if sub, ok := e.Payload.(engine.SubdomainFound); ok && !sub.Alive {
+			continue // Only live hosts count as subdomains here
+		}
+		counts[e.Type]++
+	}
+	fmt.Fprintf(humanOut, "Subdomains: %d | Open ports: %d | Web services: %d | Alerts: %d\n",
+		counts[engine.EventSubdomainFound], counts[engine.EventPortOpen],
+		counts[engine.EventHttpService], counts[engine.EventVulnFound])
+
+	for _, e := range events {
+		switch p := e.Payload.(type) {
+		case engine.PortOpen:
+			fmt.Fprintf(humanOut, "  [PORT] %s %s\n", e.Target, p)
+		case engine.ServiceDetected:
+			fmt.Fprintf(humanOut, "  [SERVICE] %s %s\n", e.Target, p)
+		case engine.HttpService:
+			fmt.Fprintf(humanOut, "  [HTTP] %s %s\n", e.Target, p)
+		case engine.VulnFound:
+			fmt.Fprintf(humanOut, "  [VULN] %s %s\n", e.Target, p)
+		case engine.PortScanSummary:
+			// Mostly silent hosts are probably firewalled (silent UDP
+			// ports are normal, so only TCP says something)
+			if p.Protocol == "tcp" && p.FilteredRatio() >= 0.9 {
+				fmt.Fprintf(humanOut, "  [FILTERED] %s %s\n", e.Target, p)
+			}
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// targetFilter matches subdomain names against a list of patterns. A pattern
+// is a glob ("*.dev.example.com") unless it starts with "re:", in which case
+// the rest is a regular expression.
+type targetFilter struct {
+	globs []string
+	res   []*regexp.Regexp
+}
+
+func newTargetFilter(patterns []string) (*targetFilter, error) {
+	f := &targetFilter{}
+	for _, p := range patterns {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		if expr, ok := strings.CutPrefix(p, "re:"); ok {
+			re, err := regexp.Compile(expr)
+			if err != nil {
+				return nil, fmt.Errorf("bad pattern %q: %w", p, err)
+			}
+			f.res = append(f.res, re)
+			continue
+		}
+		if _, err := path.Match(p, ""); err != nil {
+			return nil, fmt.Errorf("bad pattern %q: %w", p, err)
+		}
+		f.globs = append(f.globs, strings.ToLower(p))
+	}
+	return f, nil
+}
+
+func (f *targetFilter) empty() bool {
+	return len(f.globs) == 0 && len(f.res) == 0
+}
+
+func (f *targetFilter) match(name string) bool {
+	for _, g := range f.globs {
+		if ok, _ := path.Match(g, name); ok {
+			return true
+		}
+	}
+	for _, re := range f.res {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// filterTargets applies --include and --exclude to the discovered subdomains
+func filterTargets(subs []string, include, exclude *targetFilter) []string {
+	var kept []string
+	for _, s := range subs {
+		if !include.empty() && !include.match(s) {
+			continue
+		}
+		if exclude.match(s) {
+			continue
+		}
+		kept = append(kept, s)
+	}
+	return kept
+}
+
+// loadTargetPatterns reads a --deep-targets file: one name or pattern per
+// line, blank lines and # comments ignored.
+func loadTargetPatterns(filename string) (*targetFilter, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var patterns []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		patterns = append(patterns, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return newTargetFilter(patterns)
+}
+
+// splitTargets puts the subdomains matched by deep into the deep scan list
+// and everything else into the quick scan list.
+func splitTargets(subs []string, deep *targetFilter) (deepScan, quickScan []string) {
+	for _, s := range subs {
+		if deep.match(s) {
+			deepScan = append(deepScan, s)
+		} else {
+			quickScan = append(quickScan, s)
+		}
+	}
+	return deepScan, quickScan
+}
+
+// parseChoice interprets the interactive answer: "a" deep scans everything,
+// "1,3,5" deep scans those entries and quick scans the rest, and an empty
+// answer quick scans everything.
+func parseChoice(choice string, subs []string) (deepScan, quickScan []string) {
+	switch choice {
+	case "a":
+		return subs, nil
+	case "":
+		return nil, subs
+	}
+
+	selectedMap := make(map[string]bool)
+	for _, idx := range strings.Split(choice, ",") {
+		i, err := strconv.Atoi(strings.TrimSpace(idx))
+		if err == nil && i > 0 && i <= len(subs) && !selectedMap[subs[i-1]] {
+			deepScan = append(deepScan, subs[i-1])
+			selectedMap[subs[i-1]] = true
+		}
+	}
+	for _, sub := range subs {
+		if !selectedMap[sub] {
+			quickScan = append(quickScan, sub)
+		}
+	}
+	return deepScan, quickScan
+}
+
+// stdinIsTerminal reports whether someone can answer the interactive prompt.
+// A character device is not enough: /dev/null is one too.
+func stdinIsTerminal() bool {
+	return term.IsTerminal(int(os.Stdin.Fd()))
+}
@@ -1,9 +1,12 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"gorecTool/internal/engine"
 	"gorecTool/internal/modules"
+	"gorecTool/internal/rules"
+	"gorecTool/internal/store"
 	"image/color"
 	"strings"
 	"sync"
@@ -25,9 +28,13 @@ func main() {
 	myWindow := myApp.NewWindow("GoRecon - Autonomous Security Scanner")
 	myWindow.Resize(fyne.NewSize(750, 500))
 
+	// Closing the window cancels every running scan
+	ctx, cancel := context.WithCancel(context.Background())
+	myWindow.SetOnClosed(cancel)
+
 	// --- STATE ---
 	logs := binding.NewStringList()
-	results := binding.NewStringList()
+	results := binding.NewUntypedList()
 	progress := binding.NewFloat()
 	statusLabel := binding.NewString()
 	statusLabel.Set("Ready")
@@ -62,7 +69,8 @@ func main() {
 			return container.NewStack(bg, content)
 		},
 		func(i binding.DataItem, o fyne.CanvasObject) {
-			raw, _ := i.(binding.String).Get()
+			raw, _ := i.(binding.Untyped).Get()
+			evt := raw.(engine.Event)
 
 			// Unwrap
 			stack := o.(*fyne.Container)
@@ -90,32 +98,24 @@ func main() {
 			// Reset Style
 			bg.FillColor = color.Transparent
 
-			// Parse: "TYPE|TARGET|PAYLOAD"
-			parts := strings.SplitN(raw, "|", 3)
-			if len(parts) < 3 {
-				label.SetText(raw) // Fallback
-				return
-			}
-
-			msgType := parts[0]
-			target := parts[1]
-			payload := parts[2]
-
-			// Format Readable Text
-			switch msgType {
-			case "VULN":
+			// Format Readable Text straight from the typed payload
+			switch p := evt.Payload.(type) {
+			case engine.VulnFound:
 				icon.SetResource(theme.WarningIcon())
-				label.SetText(fmt.Sprintf("CRITICAL: %s found on %s", payload, target))
+				label.SetText(fmt.Sprintf("%s: %s found on %s (%s)", strings.ToUpper(p.Severity), p.Name, evt.Target, p.URL))
 				bg.FillColor = color.RGBA{R: 60, G: 0, B: 0, A: 255} // Dark Red
-			case "PORT":
+			case engine.PortOpen:
 				icon.SetResource(theme.ConfirmIcon())
-				label.SetText(fmt.Sprintf("Port Open: %s on %s", payload, target))
-			case "HTTP":
+				label.SetText(fmt.Sprintf("Port Open: %s on %s", p, evt.Target))
+			case engine.ServiceDetected:
+				icon.SetResource(theme.ComputerIcon())
+				label.SetText(fmt.Sprintf("Service: %s on %s", p, evt.Target))
+			case engine.HttpService:
 				icon.SetResource(theme.SearchIcon())
-				label.SetText(fmt.Sprintf("Web Tech: %s (%s)", payload, target))
+				label.SetText(fmt.Sprintf("Web Tech: %s | %s | %q (%s)", strings.Join(p.Tech, ", "), p.Server, p.Title, p.URL))
 			default:
 				icon.SetResource(theme.InfoIcon())
-				label.SetText(fmt.Sprintf("%s: %s", target, payload))
+				label.SetText(fmt.Sprintf("%s: %s", evt.Target, evt.Payload))
 			}
 		},
 	)
@@ -130,7 +130,7 @@ func main() {
 		logs.Append(time.Now().Format("15:04:05") + " " + msg)
 	}
 
-	startScanning := func(targets []string, deep bool) {
+	startScanning := func(domain string, targets []string, deep bool, family string, recorder *store.Recorder) {
 		progress.Set(0.0)
 		statusLabel.Set("Initializing...")
 
@@ -147,127 +147,102 @@ func main() {
 		myWindow.SetContent(container.NewVSplit(content, logArea(logScroll)))
 
 		go func() {
-			var wg sync.WaitGroup
-			wg.Add(1)
-			// 1. Calculate TOTAL operations (Granular)
-			// 1. Create the NEW BusyWg
-			var busyWg sync.WaitGroup
-
-			// 2. Keep the AnalysisWg
-			var analysisWg sync.WaitGroup
-			portsPerDomain := 20
+			// Calculate TOTAL operations (Granular)
+			ports := modules.DefaultPorts
 			if deep {
-				portsPerDomain = 65535
+				ports = modules.AllPorts()
 			}
-			// We use int64 to avoid overflow if you scan massive lists
-			totalOps := int64(len(targets) * portsPerDomain)
+			portsPerDomain := len(ports)
 			var completedOps int64 = 0
-			// CALLBACK
-			updateUI := func(evtType string, data interface{}) {
-				if evtType == "log" {
-					addLog(data.(string))
-					return
-				}
-
-				evt := data.(engine.Event)
-				prefix := "INFO"
-				switch evt.Type {
-				case engine.EventVulnFound:
-					prefix = "VULN"
-				case engine.EventPortOpen:
-					prefix = "PORT"
-				case engine.EventHttpService:
-					prefix = "HTTP"
-				}
-				// Format for the UI parser
-				results.Prepend(fmt.Sprintf("%s|%s|%s", prefix, evt.Target, evt.Payload))
+			// OBSERVER: Feed the live results and the log console
+			updateUI := &guiObserver{
+				onEvent: func(e engine.Event) { results.Prepend(e) },
+				onLog:   addLog,
 			}
 
-			brain := engine.NewEngine(&wg, &busyWg, updateUI)
+			brain := engine.NewEngine(ctx)
+			brain.AddObserver(updateUI)
+			// Same scan record as the enumeration phase (see startBtn)
+			if recorder != nil {
+				defer recorder.Store.Close()
+				brain.AddObserver(recorder)
+			}
 
 			// INIT MODULES
-			portScanner := modules.NewPortScanner(brain)
-			httpAnalyzer := modules.NewHttpAnalyzer(brain)
-			fileHunter := modules.NewFileHunter(brain)
-
-			// RULES
-			brain.AddRule(engine.Rule{
-				Name: "Web-Discovery",
-				Condition: func(e engine.Event) bool {
-					return e.Type == engine.EventPortOpen && (e.Payload == "80" || e.Payload == "443" || e.Payload == "8080")
-				},
-				Action: func(e engine.Event) {
-					analysisWg.Add(1)
-					go func() {
-						defer analysisWg.Done() // Signal done when finished
-						brain.Log(fmt.Sprintf("Analyzing Web Service on %s...", e.Target))
-						httpAnalyzer.Analyze(e.Target, 80)
-					}()
-				},
-			})
-			brain.AddRule(engine.Rule{
-				Name:      "Context-Fuzzer",
-				Condition: func(e engine.Event) bool { return e.Type == engine.EventHttpService },
-				Action: func(e engine.Event) {
-					analysisWg.Add(1)
-					go func() {
-						defer analysisWg.Done() // Signal done when finished
-						brain.Log(fmt.Sprintf("Hunting files on %s...", e.Target))
-						fileHunter.Hunt(e.Target, 80, "Apache")
-					}()
-				},
-			})
+			mods := modules.NewSet(brain)
+			portScanner := mods.Ports
+			// Picked on the selection screen (same choices as --ip-family)
+			portScanner.Family = family
+
+			// RULES (same built-in rule set as the CLI)
+			ruleSet, err := rules.Build(rules.Default(), mods.Actions())
+			if err != nil {
+				addLog(fmt.Sprintf("[!] %v", err))
+				return
+			}
+			for _, r := range ruleSet {
+				brain.AddRule(r)
+			}
 
 			go brain.Start()
+
+			// Hosts behind the same IP are scanned once
+			statusLabel.Set("Resolving targets...")
+			groups := portScanner.Plan(ctx, targets)
+			if len(groups) < len(targets) {
+				addLog(fmt.Sprintf("%d targets share %d unique addresses, scanning each address once", len(targets), len(groups)))
+			}
+			// We use int64 to avoid overflow if you scan massive lists
+			totalOps := int64(len(groups) * portsPerDomain)
+
 			// GLOBAL THROTTLING (HIGH PERFORMANCE)
 			// 1500 is roughly the limit for a standard Windows Desktop
 			// before you hit ephemeral port exhaustion (TIME_WAIT issues).
 			// If this crashes, lower to 1000. If it works, try 2000.
-			globalSem := make(chan struct{}, 1000)
+			portScanner.Limiter = make(chan struct{}, 1000)
+			portScanner.OnProgress = func(scannedCount int) {
+				// Add the batch (e.g., +50) to the total atomically
+				current := atomic.AddInt64(&completedOps, int64(scannedCount))
+
+				// Update UI Bar
+				progress.Set(float64(current) / float64(totalOps))
+			}
 			var scanWg sync.WaitGroup
 
-			for i, t := range targets {
-				statusLabel.Set(fmt.Sprintf("Queuing %s (%d/%d)...", t, i+1, len(targets)))
+			for i, g := range groups {
+				statusLabel.Set(fmt.Sprintf("Queuing %s (%d/%d)...", g.Address, i+1, len(groups)))
 
+				group := g
 				scanWg.Add(1)
-				go func(target string) {
+				brain.Go(func() {
 					defer scanWg.Done()
 
-					// Define the Callback
-					progressCallback := func(scannedCount int) {
-						// Add the batch (e.g., +50) to the total atomically
-						current := atomic.AddInt64(&completedOps, int64(scannedCount))
-
-						// Update UI Bar
-						progress.Set(float64(current) / float64(totalOps))
-					}
-
 					// Run Scan with Granular Progress
-					portScanner.ScanTarget(target, deep, globalSem, progressCallback)
+					portScanner.ScanGroup(ctx, group, ports)
 
 					// UPDATE PROGRESS SAFELY
 					current := atomic.AddInt64(&completedOps, 1)
 					progress.Set(float64(current) / float64(totalOps))
-				}(t)
+				})
 			}
 
 			scanWg.Wait()
 			statusLabel.Set("Port Scan Complete. Running Deep Analysis...")
 			progressBar.SetValue(1.0) // Force bar to full
 
-			// LAYER 1: Wait for PortOpen events to be processed
-			busyWg.Wait()
-
-			// LAYER 2: Wait for HTTP Analyzers to finish (They might Publish events!)
-			analysisWg.Wait()
-
-			// LAYER 3: Wait for those new HTTP events to be processed
-			busyWg.Wait()
-
-			// LAYER 4: Wait for FileHunters (triggered by HTTP events)
-			analysisWg.Wait()
-			close(brain.Bus)
-			wg.Wait()
+			// The engine knows when the last rule chain (HTTP -> FileHunter -> ...) is done
+			brain.Wait()
+			if recorder != nil {
+				status := store.StatusComplete
+				if ctx.Err() != nil {
+					status = store.StatusInterrupted
+				}
+				if err := recorder.Close(); err != nil {
+					addLog(fmt.Sprintf("[!] Some findings could not be saved: %v", err))
+				}
+				recorder.Store.FinishScan(recorder.ScanID, status)
+				addLog("Findings saved as scan " + recorder.ScanID)
+			}
 
 			statusLabel.Set("Complete")
 			progress.Set(1.0)
@@ -276,7 +251,7 @@ func main() {
 	}
 
 	// --- PHASE 2: SELECTION ---
-	showSelection := func(subs []string) {
+	showSelection := func(domain string, subs []string, recorder *store.Recorder) {
 		addLog(fmt.Sprintf("Enumeration complete. Found %d subdomains.", len(subs)))
 
 		checkContainer := container.NewVBox()
@@ -292,13 +267,17 @@ func main() {
 			checkContainer.Add(check)
 		}
 
-		quickBtn := widget.NewButton("Quick Scan", func() { startScanning(selected, false) })
-		deepBtn := widget.NewButton("Deep Scan", func() { startScanning(selected, true) })
+		// Address family: both by default, so dual-stack differences show up
+		familySelect := widget.NewSelect(modules.Families, nil)
+		familySelect.SetSelected(modules.FamilyBoth)
+
+		quickBtn := widget.NewButton("Quick Scan", func() { startScanning(domain, selected, false, familySelect.Selected, recorder) })
+		deepBtn := widget.NewButton("Deep Scan", func() { startScanning(domain, selected, true, familySelect.Selected, recorder) })
 		deepBtn.Importance = widget.HighImportance
 
 		content := container.NewBorder(
 			widget.NewLabelWithStyle("Select Targets", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
-			container.NewHBox(quickBtn, deepBtn),
+			container.NewHBox(quickBtn, deepBtn, widget.NewLabel("IP family:"), familySelect),
 			nil, nil,
 			container.NewVScroll(checkContainer),
 		)
@@ -315,21 +294,50 @@ func main() {
 		}
 		input.Disable()
 
-		cb := func(t string, d interface{}) {
-			if t == "log" {
-				addLog(d.(string))
+		go func() {
+			brain := engine.NewEngine(ctx)
+			brain.AddObserver(&guiObserver{
+				// Takeover findings show up with the scan results later
+				onEvent: func(e engine.Event) {
+					if e.Type == engine.EventVulnFound {
+						results.Prepend(e)
+					}
+				},
+				onLog: addLog,
+			})
+
+			// Same rules as the scan phase, so SUBDOMAIN_FOUND runs the takeover check
+			mods := modules.NewSet(brain)
+			ruleSet, err := rules.Build(rules.Default(), mods.Actions())
+			if err != nil {
+				addLog(fmt.Sprintf("[!] %v", err))
+				return
+			}
+			for _, r := range ruleSet {
+				brain.AddRule(r)
 			}
-		}
 
-		go func() {
-			dummyWg := &sync.WaitGroup{}
-			dummyWg.Add(1)
-			dummyBusyWg := &sync.WaitGroup{}
-			brain := engine.NewEngine(dummyWg, dummyBusyWg, cb)
-			subMod := modules.NewSubdomainModule(brain)
-
-			subs := subMod.Run(input.Text)
-			showSelection(subs)
+			// Save findings to the same database the CLI uses (`gorecon
+			// history`). The scan covers both phases: it is created here so
+			// subdomains and DNS records are recorded, and finished once
+			// the port scan is done.
+			var recorder *store.Recorder
+			if db, err := store.Open(store.DefaultPath()); err != nil {
+				addLog(fmt.Sprintf("[!] Findings will not be saved: %v", err))
+			} else if scan, err := db.CreateScan(input.Text); err != nil {
+				addLog(fmt.Sprintf("[!] Findings will not be saved: %v", err))
+				db.Close()
+			} else {
+				recorder = store.NewRecorder(db, scan.ID)
+				brain.AddObserver(recorder)
+			}
+			go brain.Start()
+
+			subs := mods.Subdomains.Run(ctx, input.Text)
+
+			// Drain the SUBDOMAIN_FOUND events (and takeover checks) before moving on
+			brain.Wait()
+			showSelection(input.Text, subs, recorder)
 		}()
 	})
 
@@ -352,3 +360,21 @@ func logArea(content fyne.CanvasObject) fyne.CanvasObject {
 		content,
 	)
 }
+
+// guiObserver adapts the shared engine's Observer interface to Fyne bindings
+type guiObserver struct {
+	onEvent func(e engine.Event)
+	onLog   func(message string)
+}
+
+func (g *guiObserver) OnEvent(e engine.Event) {
+	if g.onEvent != nil {
+		g.onEvent(e)
+	}
+}
+
+func (g *guiObserver) OnLog(message string) {
+	if g.onLog != nil {
+		g.onLog(message)
+	}
+}
@@ -0,0 +1,146 @@
+// Package diff compares the findings of two scans and reports what was
+// added, what disappeared and what changed in between.
+package diff
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gorecTool/internal/engine"
+)
+
+// Change is a finding present in both scans whose details differ
+type Change struct {
+	Old    engine.Event
+	New    engine.Event
+	Fields []string // Human readable "field: old -> new" descriptions
+}
+
+// Result is the delta between two scans
+type Result struct {
+	Added   []engine.Event
+	Removed []engine.Event
+	Changed []Change
+}
+
+// Empty reports whether nothing changed
+func (r Result) Empty() bool {
+	return len(r.Added) == 0 && len(r.Removed) == 0 && len(r.Changed) == 0
+}
+
+// Compare diffs the events of an older and a newer scan. Findings are
+// matched by event type, target and a per-type identity (port, URL, ...).
+func Compare(older, newer []engine.Event) Result {
+	oldByKey := index(older)
+	newByKey := index(newer)
+
+	var r Result
+	for _, k := range sortedKeys(newByKey) {
+		n := newByKey[k]
+		o, ok := oldByKey[k]
+		if !ok {
+			r.Added = append(r.Added, n)
+			continue
+		}
+		if fields := changedFields(o, n); len(fields) > 0 {
+			r.Changed = append(r.Changed, Change{Old: o, New: n, Fields: fields})
+		}
+	}
+	for _, k := range sortedKeys(oldByKey) {
+		if _, ok := newByKey[k]; !ok {
+			r.Removed = append(r.Removed, oldByKey[k])
+		}
+	}
+	return r
+}
+
+// Key identifies a finding across scans
+func Key(e engine.Event) string {
+	id := ""
+	switch p := e.Payload.(type) {
+	case engine.PortOpen:
+		// Keyed per family, so a port open on IPv6 only shows up on its own
+		id = fmt.Sprintf("%d/%s", p.Port, engine.Transport(p.Protocol, p.Family))
+	case engine.HttpService:
+		// Per family too: a dual-stack host is analyzed once per family.
+		// IPv4 keeps the bare port, the key scans recorded before had.
+		id = fmt.Sprintf("%d", p.Port)
+		if family := engine.FamilyOf(p.IP); family == engine.FamilyIPv6 {
+			id += "/" + engine.Transport("tcp", family)
+		}
+	case engine.VulnFound:
+		id = p.Name + " " + p.URL
+	case engine.DNSRecord:
+		id = p.Type + " " + p.Value
+	case engine.PortState:
+		id = fmt.Sprintf("%d/%s", p.Port, engine.Transport(p.Protocol, p.Family))
+	case engine.ServiceDetected:
+		id = fmt.Sprintf("%d/%s", p.Port, engine.Transport(p.Protocol, p.Family))
+	case engine.PortScanSummary:
+		id = engine.Transport(p.Protocol, p.Family)
+	}
+	return string(e.Type) + "|" + e.Target + "|" + id
+}
+
+// index keeps the last event per key (later events carry fresher details)
+func index(events []engine.Event) map[string]engine.Event {
+	m := make(map[string]engine.Event, len(events))
+	for _, e := range events {
+		m[Key(e)] = e
+	}
+	return m
+}
+
+func changedFields(o, n engine.Event) []string {
+	var fields []string
+	cmp := func(name, a, b string) {
+		if a != b {
+			fields = append(fields, fmt.Sprintf("%s: %q -> %q", name, a, b))
+		}
+	}
+
+	switch np := n.Payload.(type) {
+	case engine.HttpService:
+		op := o.Payload.(engine.HttpService)
+		cmp("status", fmt.Sprint(op.StatusCode), fmt.Sprint(np.StatusCode))
+		cmp("server", op.Server, np.Server)
+		cmp("tech", joinSorted(op.Tech), joinSorted(np.Tech))
+		cmp("title", op.Title, np.Title)
+		cmp("final url", op.FinalURL, np.FinalURL)
+	case engine.SubdomainFound:
+		op := o.Payload.(engine.SubdomainFound)
+		cmp("alive", fmt.Sprint(op.Alive), fmt.Sprint(np.Alive))
+		cmp("addresses", joinSorted(op.Addresses), joinSorted(np.Addresses))
+	case engine.VulnFound:
+		op := o.Payload.(engine.VulnFound)
+		cmp("severity", op.Severity, np.Severity)
+	case engine.PortState:
+		op := o.Payload.(engine.PortState)
+		cmp("state", op.State, np.State)
+	case engine.ServiceDetected:
+		op := o.Payload.(engine.ServiceDetected)
+		cmp("service", op.Service, np.Service)
+		cmp("product", strings.TrimSpace(op.Product+" "+op.Version), strings.TrimSpace(np.Product+" "+np.Version))
+	case engine.PortScanSummary:
+		op := o.Payload.(engine.PortScanSummary)
+		cmp("open", fmt.Sprint(op.Open), fmt.Sprint(np.Open))
+		cmp("filtered", fmt.Sprint(op.Filtered), fmt.Sprint(np.Filtered))
+	}
+	return fields
+}
+
+func joinSorted(list []string) string {
+	sorted := append([]string(nil), list...)
+	sort.Strings(sorted)
+	return strings.Join(sorted, ", ")
+}
+
+func sortedKeys(m map[string]engine.Event) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -1,8 +1,10 @@
 package engine
 
 import (
+	"context"
 	"fmt"
 	"sync"
+	"time"
 )
 
 // 1. The Event (The basic unit of information)
@@ -13,63 +15,212 @@ const (
 	EventHttpService    EventType = "HTTP_SERVICE"
 	EventVulnFound      EventType = "VULN_FOUND"
 	EventSubdomainFound EventType = "SUBDOMAIN_FOUND"
+	EventDNSRecord      EventType = "DNS_RECORD"
+	EventPortState      EventType = "PORT_STATE"
+	EventPortSummary    EventType = "PORT_SCAN_SUMMARY"
+	EventServiceFound   EventType = "SERVICE_DETECTED"
 )
 
+// EventTypes lists every EventType the engine knows about
+var EventTypes = []EventType{
+	EventPortOpen,
+	EventHttpService,
+	EventVulnFound,
+	EventSubdomainFound,
+	EventDNSRecord,
+	EventPortState,
+	EventPortSummary,
+	EventServiceFound,
+}
+
+// Known reports whether t is one of EventTypes
+func (t EventType) Known() bool {
+	for _, k := range EventTypes {
+		if t == k {
+			return true
+		}
+	}
+	return false
+}
+
 type Event struct {
 	Type    EventType
-	Target  string // IP or Domain
-	Payload string // Extra info (e.g., "80", "Apache 2.4")
+	Target  string    // IP or Domain
+	Time    time.Time // Set by Publish if left empty
+	Payload Payload   // Typed body, one concrete type per EventType (see payload.go)
 }
 
 // 2. The Rule (The Logic)
-// A Rule checks an event and decides if it should trigger an Action
+// A Rule checks an event and decides if it should trigger an Action.
+// The engine runs each Action in its own goroutine and keeps counting it as
+// in-flight work until it returns, so Actions should do their work
+// synchronously instead of spawning goroutines of their own. The context
+// passed to Action is the engine's; it is cancelled on Ctrl-C or deadline.
 type Rule struct {
 	Name      string
 	Condition func(e Event) bool
-	Action    func(e Event) // In reality, this would enqueue a new Job
+	Action    func(ctx context.Context, e Event)
+}
+
+// Scope limits which targets the engine's modules may touch. InScope is
+// asked about every event target before a rule acts on it; Excluded about
+// the addresses a hostname resolved to.
+type Scope interface {
+	InScope(target string) bool
+	Excluded(target string) bool
 }
 
 // 3. The Brain (The Engine)
 type DecisionEngine struct {
-	Rules []Rule
-	Bus   chan Event
-	wg    *sync.WaitGroup
+	Rules     []Rule
+	Bus       chan Event
+	Observers []Observer
+
+	// scope, if set, keeps every action on authorized targets
+	scope Scope
+
+	// inflight counts events sitting in (or being dispatched from) the Bus,
+	// running rule Actions and work started through Go. Every Action is
+	// added before the event that triggered it is marked done, so the
+	// counter can only reach zero once nothing is left that could Publish.
+	inflight sync.WaitGroup
+	stopped  chan struct{} // closed when Start returns
+
+	// ctx bounds the whole scan. Once it is cancelled Publish drops events,
+	// Start stops triggering rules and every module unwinds on its own.
+	ctx context.Context
 }
 
-func NewEngine(wg *sync.WaitGroup) *DecisionEngine {
+func NewEngine(ctx context.Context) *DecisionEngine {
 	return &DecisionEngine{
-		Rules: []Rule{},
-		Bus:   make(chan Event, 1000), // Buffered channel
-		wg:    wg,
+		Rules:   []Rule{},
+		Bus:     make(chan Event, 1000), // Buffered channel
+		stopped: make(chan struct{}),
+		ctx:     ctx,
 	}
 }
 
+// Context returns the context the engine (and every Action) runs under
+func (de *DecisionEngine) Context() context.Context {
+	return de.ctx
+}
+
 // AddRule registers a new logic pattern
 func (de *DecisionEngine) AddRule(r Rule) {
 	de.Rules = append(de.Rules, r)
 }
 
-// Start begins the listening loop
+// AddObserver plugs a front-end (console, GUI, ...) into the engine.
+// Register observers before calling Start.
+func (de *DecisionEngine) AddObserver(o Observer) {
+	de.Observers = append(de.Observers, o)
+}
+
+// SetScope restricts every rule action and module to targets in s.
+// Call it before Start.
+func (de *DecisionEngine) SetScope(s Scope) {
+	de.scope = s
+}
+
+// HasScope reports whether a scope was set with SetScope
+func (de *DecisionEngine) HasScope() bool {
+	return de.scope != nil
+}
+
+// InScope reports whether modules may act on target (always true without
+// a scope)
+func (de *DecisionEngine) InScope(target string) bool {
+	return de.scope == nil || de.scope.InScope(target)
+}
+
+// Excluded reports whether the scope explicitly excludes target, e.g. an
+// address an in-scope hostname resolved to
+func (de *DecisionEngine) Excluded(target string) bool {
+	return de.scope != nil && de.scope.Excluded(target)
+}
+
+// Start begins the listening loop. It returns once Wait has shut the engine down.
 func (de *DecisionEngine) Start() {
-	defer de.wg.Done()
-	fmt.Println("[Engine] Decision Engine Started. Listening for events...")
+	defer close(de.stopped)
+	de.Log("[Engine] Decision Engine Started. Listening for events...")
 
 	for event := range de.Bus {
-		// Log every event
-		fmt.Printf("[Log] Received Event: %s on %s (%s)\n", event.Type, event.Target, event.Payload)
+		// Hand every event to the front-ends first
+		for _, o := range de.Observers {
+			o.OnEvent(event)
+		}
 
 		// Check against ALL rules (The Logic)
+		// After cancellation we still drain (and show) what was found,
+		// but don't start any new work.
 		for _, rule := range de.Rules {
-			if rule.Condition(event) {
-				fmt.Printf("[Logic] Rule '%s' Triggered! Executing Action.\n", rule.Name)
-				// Run the action (usually distinct from the engine in real code)
-				go rule.Action(event)
+			if de.ctx.Err() == nil && rule.Condition(event) {
+				// Findings about out-of-scope hosts are kept, never acted on
+				if !de.InScope(event.Target) {
+					de.Logf("[Scope] %s is out of scope, not running '%s'.", event.Target, rule.Name)
+					continue
+				}
+				de.Logf("[Logic] Rule '%s' Triggered! Executing Action.", rule.Name)
+				action, e := rule.Action, event
+				de.Go(func() { action(de.ctx, e) })
 			}
 		}
+
+		// EVENT PROCESSED: Mark as done (after its Actions were counted)
+		de.inflight.Done()
 	}
 }
 
-// Publish is used by modules to send data to the brain
+// Go runs fn in a new goroutine and counts it as in-flight work, so Wait
+// won't return while fn might still Publish. Use it for the work that
+// seeds the pipeline (subdomain enumeration, port scans, ...).
+func (de *DecisionEngine) Go(fn func()) {
+	de.inflight.Add(1)
+	go func() {
+		defer de.inflight.Done()
+		fn()
+	}()
+}
+
+// Wait blocks until the engine is quiescent (no queued events, no running
+// Actions, no work started through Go), then closes the Bus and waits for
+// Start to return. This holds for rule chains of any depth. Seed the
+// pipeline (Go / Publish) before calling Wait.
+func (de *DecisionEngine) Wait() {
+	de.inflight.Wait()
+	close(de.Bus)
+	<-de.stopped
+}
+
+// Done is closed once the engine has shut down after Wait.
+func (de *DecisionEngine) Done() <-chan struct{} {
+	return de.stopped
+}
+
+// Log sends a plain text message to every observer
+func (de *DecisionEngine) Log(message string) {
+	for _, o := range de.Observers {
+		o.OnLog(message)
+	}
+}
+
+// Logf is Log with fmt.Sprintf formatting
+func (de *DecisionEngine) Logf(format string, args ...interface{}) {
+	de.Log(fmt.Sprintf(format, args...))
+}
+
+// Publish is used by modules to send data to the brain.
+// If the engine's context is cancelled while the Bus is full the event is dropped.
 func (de *DecisionEngine) Publish(e Event) {
-	de.Bus <- e
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	// EVENT ADDED: Mark as busy
+	de.inflight.Add(1)
+	select {
+	case de.Bus <- e:
+	case <-de.ctx.Done():
+		de.inflight.Done()
+	}
 }
@@ -0,0 +1,86 @@
+package engine
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// hop is a rule that, after a pause, turns an event of type on into one of
+// type next. The pause leaves the Bus empty between hops, which is when a
+// Wait that only watched the queue would return too early.
+func hop(de *DecisionEngine, on, next EventType, payload Payload) Rule {
+	return Rule{
+		Name:      string(on) + " -> " + string(next),
+		Condition: func(e Event) bool { return e.Type == on },
+		Action: func(ctx context.Context, e Event) {
+			time.Sleep(20 * time.Millisecond)
+			de.Publish(Event{Type: next, Target: e.Target, Payload: payload})
+		},
+	}
+}
+
+func TestWaitRuleChain(t *testing.T) {
+	de := NewEngine(context.Background())
+	seen := NewCollector()
+	de.AddObserver(seen)
+	de.AddRule(hop(de, EventPortOpen, EventServiceFound, ServiceDetected{Port: 80, Protocol: "tcp", Service: "http"}))
+	de.AddRule(hop(de, EventServiceFound, EventHttpService, HttpService{Port: 80, StatusCode: 200}))
+	de.AddRule(hop(de, EventHttpService, EventVulnFound, VulnFound{Name: "Sensitive File", Severity: "HIGH", Port: 80}))
+	go de.Start()
+
+	de.Go(func() {
+		de.Publish(Event{Type: EventPortOpen, Target: "www.example.com", Payload: PortOpen{Port: 80, Protocol: "tcp"}})
+	})
+	de.Wait()
+
+	var got []EventType
+	for _, e := range seen.Events() {
+		got = append(got, e.Type)
+	}
+	want := []EventType{EventPortOpen, EventServiceFound, EventHttpService, EventVulnFound}
+	if len(got) != len(want) {
+		t.Fatalf("Wait returned after %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("events %v, want %v", got, want)
+		}
+	}
+	select {
+	case <-de.Done():
+	default:
+		t.Error("Start is still running after Wait")
+	}
+}
+
+func TestWaitCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	de := NewEngine(ctx)
+
+	// A module that publishes until cancelled, and a rule whose Action
+	// blocks until then too
+	de.AddRule(Rule{
+		Name:      "block",
+		Condition: func(e Event) bool { return e.Type == EventPortOpen },
+		Action:    func(ctx context.Context, e Event) { <-ctx.Done() },
+	})
+	go de.Start()
+	de.Go(func() {
+		for ctx.Err() == nil {
+			de.Publish(Event{Type: EventPortOpen, Target: "www.example.com", Payload: PortOpen{Port: 80, Protocol: "tcp"}})
+		}
+	})
+
+	time.AfterFunc(50*time.Millisecond, cancel)
+	waited := make(chan struct{})
+	go func() {
+		de.Wait()
+		close(waited)
+	}()
+	select {
+	case <-waited:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Wait still blocked 2s after the context was cancelled")
+	}
+}
@@ -0,0 +1,59 @@
+package engine
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// Observer receives everything the engine sees. The CLI and the Fyne GUI are
+// both just observers, so they share one engine and one set of modules.
+// Observers are called from many goroutines and must be safe for that.
+type Observer interface {
+	OnEvent(e Event)
+	OnLog(message string)
+}
+
+// ConsoleObserver prints events and log lines as plain text (the CLI output).
+type ConsoleObserver struct {
+	Out io.Writer
+}
+
+func NewConsoleObserver() *ConsoleObserver {
+	return &ConsoleObserver{Out: os.Stdout}
+}
+
+func (c *ConsoleObserver) OnEvent(e Event) {
+	fmt.Fprintf(c.Out, "[Log] Received Event: %s on %s (%s)\n", e.Type, e.Target, e.Payload)
+}
+
+func (c *ConsoleObserver) OnLog(message string) {
+	fmt.Fprintln(c.Out, message)
+}
+
+// Collector keeps every event it sees, e.g. to print a summary at the end
+// of a scan (including a partial one after Ctrl-C).
+type Collector struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func NewCollector() *Collector {
+	return &Collector{}
+}
+
+func (c *Collector) OnEvent(e Event) {
+	c.mu.Lock()
+	c.events = append(c.events, e)
+	c.mu.Unlock()
+}
+
+func (c *Collector) OnLog(message string) {}
+
+// Events returns a copy of everything collected so far
+func (c *Collector) Events() []Event {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Event(nil), c.events...)
+}
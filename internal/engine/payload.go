@@ -0,0 +1,386 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Payload is the typed body of an Event. Every EventType has exactly one
+// payload type, so consumers can type-assert instead of parsing strings:
+//
+//	EventPortOpen       -> PortOpen
+//	EventHttpService    -> HttpService
+//	EventVulnFound      -> VulnFound
+//	EventSubdomainFound -> SubdomainFound
+//	EventDNSRecord      -> DNSRecord
+//	EventPortState      -> PortState
+//	EventPortSummary    -> PortScanSummary
+//	EventServiceFound   -> ServiceDetected
+//
+// A new EventType also needs an entry in EventTypes and in decodePayload.
+type Payload interface {
+	// String gives a short human readable summary for logs.
+	String() string
+}
+
+// Address families of scanned ports. Events recorded before the scanner
+// knew about IPv6 have no family and were IPv4.
+const (
+	FamilyIPv4 = "ipv4"
+	FamilyIPv6 = "ipv6"
+)
+
+// FamilyOf returns FamilyIPv4 or FamilyIPv6 for an IP address, "" for
+// anything else
+func FamilyOf(ip string) string {
+	parsed := net.ParseIP(ip)
+	switch {
+	case parsed == nil:
+		return ""
+	case parsed.To4() != nil:
+		return FamilyIPv4
+	}
+	return FamilyIPv6
+}
+
+// Transport names a protocol on an address family the way netstat does:
+// "tcp" over IPv4, "tcp6" over IPv6
+func Transport(protocol, family string) string {
+	if family == FamilyIPv6 {
+		return protocol + "6"
+	}
+	return protocol
+}
+
+// PortOpen is published by the PortScanner for every open port.
+type PortOpen struct {
+	Port     int    `json:"port"`
+	Protocol string `json:"protocol"`         // Transport protocol, "tcp" or "udp"
+	IP       string `json:"ip,omitempty"`     // Address that was scanned, if the host was resolved first
+	Family   string `json:"family,omitempty"` // FamilyIPv4 or FamilyIPv6
+}
+
+func (p PortOpen) String() string {
+	return fmt.Sprintf("%d/%s", p.Port, Transport(p.Protocol, p.Family))
+}
+
+// HttpService is published by the HttpAnalyzer once a web server answered.
+type HttpService struct {
+	Port       int      `json:"port"`
+	Scheme     string   `json:"scheme"` // Scheme of FinalURL, "http" or "https"
+	URL        string   `json:"url"`    // Where the probe started, e.g. http://host:80
+	StatusCode int      `json:"status_code"`
+	Title      string   `json:"title"`
+	Server     string   `json:"server"`
+	Tech       []string `json:"tech"`
+
+	// Redirects are the URLs that answered with a redirect, in order,
+	// starting with URL. Empty if URL answered directly.
+	Redirects []string `json:"redirects,omitempty"`
+	// FinalURL is the page that answered (StatusCode, Title, ... describe
+	// it). Empty in events recorded before redirects were tracked.
+	FinalURL string `json:"final_url,omitempty"`
+	// HTTPSUpgrade is set when plain HTTP redirected to HTTPS
+	HTTPSUpgrade bool `json:"https_upgrade,omitempty"`
+	// IP is the address the host was reached on, if the port scan resolved it
+	IP string `json:"ip,omitempty"`
+}
+
+func (h HttpService) String() string {
+	tech := "Unknown"
+	if len(h.Tech) > 0 {
+		tech = strings.Join(h.Tech, ", ")
+	}
+	where := h.URL
+	if h.FinalURL != "" && h.FinalURL != h.URL {
+		where += " -> " + h.FinalURL
+	}
+	return fmt.Sprintf("[%d] %s | Server: %s | Tech: %s", h.StatusCode, where, h.Server, tech)
+}
+
+// Root is the base URL of the application behind the service: the
+// directory of FinalURL (or URL), without query or trailing slash, so
+// paths can be appended to it.
+func (h HttpService) Root() string {
+	raw := h.FinalURL
+	if raw == "" {
+		raw = h.URL
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return strings.TrimSuffix(raw, "/")
+	}
+	u.RawQuery, u.Fragment = "", ""
+	u.Path = u.Path[:strings.LastIndex(u.Path, "/")+1]
+	u.RawPath = ""
+	return strings.TrimSuffix(u.String(), "/")
+}
+
+// HasTech reports whether the given technology was fingerprinted.
+func (h HttpService) HasTech(name string) bool {
+	for _, t := range h.Tech {
+		if t == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Severity levels used by VulnFound, ordered from most to least urgent.
+const (
+	SeverityHigh   = "high"
+	SeverityMedium = "medium"
+	SeverityLow    = "low"
+	SeverityInfo   = "info"
+)
+
+// VulnFound is published by modules that found something worth alerting on.
+type VulnFound struct {
+	Name     string `json:"name"` // What was found, e.g. "Sensitive File"
+	Severity string `json:"severity"`
+	Port     int    `json:"port,omitempty"`
+	URL      string `json:"url,omitempty"`
+	Evidence string `json:"evidence"`
+}
+
+func (v VulnFound) String() string {
+	return fmt.Sprintf("%s (%s): %s", v.Name, v.Severity, v.Evidence)
+}
+
+// SubdomainFound is published by the SubdomainModule for every discovered
+// name. Names that don't resolve are published too (Alive false), since
+// dangling records are where takeovers hide.
+type SubdomainFound struct {
+	Sources   []string `json:"sources"` // Every source that reported the name
+	Alive     bool     `json:"alive"`
+	Addresses []string `json:"addresses"`
+}
+
+func (s SubdomainFound) String() string {
+	if !s.Alive {
+		return fmt.Sprintf("not resolving, via %s", strings.Join(s.Sources, ", "))
+	}
+	return fmt.Sprintf("%s via %s", strings.Join(s.Addresses, ", "), strings.Join(s.Sources, ", "))
+}
+
+// UnmarshalJSON also accepts older scans, which had a single "source" field
+// and only recorded live hosts
+func (s *SubdomainFound) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Source    string   `json:"source"`
+		Sources   []string `json:"sources"`
+		Alive     *bool    `json:"alive"`
+		Addresses []string `json:"addresses"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	s.Sources, s.Addresses = raw.Sources, raw.Addresses
+	if len(s.Sources) == 0 && raw.Source != "" {
+		s.Sources = []string{raw.Source}
+	}
+	s.Alive = raw.Alive == nil || *raw.Alive
+	return nil
+}
+
+// DNSRecord is one DNS record of a live host, published by the
+// SubdomainModule (one event per record).
+type DNSRecord struct {
+	Type  string `json:"type"`  // A, AAAA, CNAME, MX, TXT or NS
+	Value string `json:"value"` // Address, target name, "10 mx.example.com" for MX, or TXT data
+}
+
+func (d DNSRecord) String() string {
+	return fmt.Sprintf("%s %s", d.Type, d.Value)
+}
+
+// ServiceDetected is published by the ServiceDetector once it identified
+// what listens on an open port.
+type ServiceDetected struct {
+	Port     int    `json:"port"`
+	Protocol string `json:"protocol"`
+	IP       string `json:"ip,omitempty"`     // Address the service was probed on
+	Family   string `json:"family,omitempty"` // FamilyIPv4 or FamilyIPv6
+	Service  string `json:"service"`          // e.g. "ssh", "http", "mysql"; "tls" for unknown TLS services
+	TLS      bool   `json:"tls"`              // Spoken over TLS (https is "http" with TLS)
+	Product  string `json:"product,omitempty"`
+	Version  string `json:"version,omitempty"`
+	Banner   string `json:"banner,omitempty"` // First line the service sent, if any
+}
+
+func (s ServiceDetected) String() string {
+	name := s.Service
+	if s.TLS && s.Service != "tls" {
+		name += "+tls"
+	}
+	out := fmt.Sprintf("%d/%s %s", s.Port, Transport(s.Protocol, s.Family), name)
+	if s.Product != "" {
+		out += " " + strings.TrimSpace(s.Product+" "+s.Version)
+	}
+	return out
+}
+
+// Port states, told apart by how a connection attempt ended
+const (
+	StateOpen     = "open"
+	StateClosed   = "closed"   // Actively refused (RST)
+	StateFiltered = "filtered" // No answer or unreachable: something drops the packets
+
+	// StateOpenFiltered is a UDP port that stayed silent: an open service
+	// ignoring our probe looks the same as a firewall dropping it
+	StateOpenFiltered = "open|filtered"
+)
+
+// PortState is published by the PortScanner for closed and filtered ports
+// when asked to (open ports are always PortOpen events).
+type PortState struct {
+	Port     int    `json:"port"`
+	Protocol string `json:"protocol"`
+	State    string `json:"state"` // StateClosed, StateFiltered or StateOpenFiltered
+	IP       string `json:"ip,omitempty"`
+	Family   string `json:"family,omitempty"`
+}
+
+func (p PortState) String() string {
+	return fmt.Sprintf("%d/%s %s", p.Port, Transport(p.Protocol, p.Family), p.State)
+}
+
+// PortScanSummary is published by the PortScanner once per host and scan
+type PortScanSummary struct {
+	Protocol string `json:"protocol"`
+	IP       string `json:"ip,omitempty"`
+	Family   string `json:"family,omitempty"`
+	Scanned  int    `json:"scanned"`
+	Open     int    `json:"open"`
+	Closed   int    `json:"closed"`
+	Filtered int    `json:"filtered"`
+}
+
+func (s PortScanSummary) String() string {
+	return fmt.Sprintf("%d %s ports: %d open, %d closed, %d filtered (%.0f%%)",
+		s.Scanned, Transport(s.Protocol, s.Family), s.Open, s.Closed, s.Filtered, 100*s.FilteredRatio())
+}
+
+// FilteredRatio is the share of scanned ports that never answered. A high
+// ratio means the host sits behind a firewall (for TCP; silent UDP ports,
+// counted as Filtered, are the norm).
+func (s PortScanSummary) FilteredRatio() float64 {
+	if s.Scanned == 0 {
+		return 0
+	}
+	return float64(s.Filtered) / float64(s.Scanned)
+}
+
+// Port returns the port the event is about, for payloads that carry one
+func (e Event) Port() (int, bool) {
+	switch p := e.Payload.(type) {
+	case PortOpen:
+		return p.Port, true
+	case PortState:
+		return p.Port, true
+	case ServiceDetected:
+		return p.Port, true
+	case HttpService:
+		return p.Port, true
+	case VulnFound:
+		return p.Port, p.Port != 0
+	}
+	return 0, false
+}
+
+// IP returns the address the event's port was found on, "" if unknown
+func (e Event) IP() string {
+	switch p := e.Payload.(type) {
+	case PortOpen:
+		return p.IP
+	case PortState:
+		return p.IP
+	case PortScanSummary:
+		return p.IP
+	case ServiceDetected:
+		return p.IP
+	case HttpService:
+		return p.IP
+	}
+	return ""
+}
+
+// Protocol returns the transport ("tcp" or "udp") of the port the event is
+// about, or "" if it has none. Web services are always TCP.
+func (e Event) Protocol() string {
+	switch p := e.Payload.(type) {
+	case PortOpen:
+		return p.Protocol
+	case PortState:
+		return p.Protocol
+	case PortScanSummary:
+		return p.Protocol
+	case ServiceDetected:
+		return p.Protocol
+	case HttpService:
+		return "tcp"
+	}
+	return ""
+}
+
+// eventJSON is the stable on-disk / on-the-wire shape of an Event
+type eventJSON struct {
+	Type    EventType       `json:"type"`
+	Target  string          `json:"target"`
+	Time    time.Time       `json:"time"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+func (e Event) MarshalJSON() ([]byte, error) {
+	payload, err := json.Marshal(e.Payload)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(eventJSON{Type: e.Type, Target: e.Target, Time: e.Time, Payload: payload})
+}
+
+// UnmarshalJSON restores the concrete payload type from the event type
+func (e *Event) UnmarshalJSON(data []byte) error {
+	var raw eventJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	payload, err := decodePayload(raw.Type, raw.Payload)
+	if err != nil {
+		return err
+	}
+	*e = Event{Type: raw.Type, Target: raw.Target, Time: raw.Time, Payload: payload}
+	return nil
+}
+
+func decodePayload(t EventType, raw json.RawMessage) (Payload, error) {
+	switch t {
+	case EventPortOpen:
+		return decodeAs[PortOpen](raw)
+	case EventHttpService:
+		return decodeAs[HttpService](raw)
+	case EventVulnFound:
+		return decodeAs[VulnFound](raw)
+	case EventSubdomainFound:
+		return decodeAs[SubdomainFound](raw)
+	case EventDNSRecord:
+		return decodeAs[DNSRecord](raw)
+	case EventPortState:
+		return decodeAs[PortState](raw)
+	case EventPortSummary:
+		return decodeAs[PortScanSummary](raw)
+	case EventServiceFound:
+		return decodeAs[ServiceDetected](raw)
+	}
+	return nil, fmt.Errorf("unknown event type %q", t)
+}
+
+func decodeAs[T Payload](raw json.RawMessage) (Payload, error) {
+	var p T
+	err := json.Unmarshal(raw, &p)
+	return p, err
+}
@@ -0,0 +1,116 @@
+package modules
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Dial timeout bounds for the RTT based estimate
+const (
+	initialDialTimeout = 1 * time.Second
+	minDialTimeout     = 150 * time.Millisecond
+	maxDialTimeout     = 3 * time.Second
+)
+
+// rttEstimator tracks the round trip time to one host the way TCP does
+// (RFC 6298) and turns it into a dial timeout. Every answer counts as a
+// sample, a SYN-ACK (open) as well as a RST (closed); silence does not.
+type rttEstimator struct {
+	mu      sync.Mutex
+	srtt    time.Duration
+	rttvar  time.Duration
+	samples int
+}
+
+func (r *rttEstimator) observe(rtt time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.samples == 0 {
+		r.srtt, r.rttvar = rtt, rtt/2
+	} else {
+		diff := r.srtt - rtt
+		if diff < 0 {
+			diff = -diff
+		}
+		r.rttvar = (3*r.rttvar + diff) / 4
+		r.srtt = (7*r.srtt + rtt) / 8
+	}
+	r.samples++
+}
+
+// timeout is how long a dial may take before the port counts as filtered
+func (r *rttEstimator) timeout() time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.samples == 0 {
+		return initialDialTimeout
+	}
+	t := r.srtt + 4*r.rttvar
+	if t < minDialTimeout {
+		t = minDialTimeout
+	}
+	if t > maxDialTimeout {
+		t = maxDialTimeout
+	}
+	return t
+}
+
+// backoff slows every dial of a scanner down while the OS is out of
+// sockets, doubling the pause on each resource error and resetting on the
+// next success
+type backoff struct {
+	mu    sync.Mutex
+	delay time.Duration
+}
+
+const (
+	minBackoff = 50 * time.Millisecond
+	maxBackoff = 2 * time.Second
+)
+
+// fail records a resource error, lengthening the delay every dial waits
+// out; first is true when this starts a new backoff episode
+func (b *backoff) fail() (first bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	first = b.delay == 0
+	if first {
+		b.delay = minBackoff
+	} else if b.delay < maxBackoff {
+		b.delay *= 2
+	}
+	return first
+}
+
+// wait blocks for the current backoff delay, if any, so every dial of the
+// scanner holds back while one of them is out of sockets. It returns false
+// if ctx was cancelled first.
+func (b *backoff) wait(ctx context.Context) bool {
+	b.mu.Lock()
+	delay := b.delay
+	b.mu.Unlock()
+	if delay == 0 {
+		return true
+	}
+	return sleepCtx(ctx, delay)
+}
+
+func (b *backoff) succeed() {
+	b.mu.Lock()
+	b.delay = 0
+	b.mu.Unlock()
+}
+
+// isResourceError reports dial failures caused by our own host running out
+// of file descriptors or ephemeral ports, rather than by the target
+func isResourceError(err error) bool {
+	return errors.Is(err, syscall.EMFILE) || errors.Is(err, syscall.ENFILE) ||
+		errors.Is(err, syscall.EADDRNOTAVAIL) || errors.Is(err, syscall.ENOBUFS)
+}
+
+func isRefused(err error) bool {
+	return errors.Is(err, syscall.ECONNREFUSED)
+}
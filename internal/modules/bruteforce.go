@@ -0,0 +1,199 @@
+package modules
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// A small, high-value wordlist for when passive recon finds nothing and no
+// --wordlist was given
+var commonSubs = []string{
+	"www", "mail", "remote", "blog", "webmail", "server",
+	"ns1", "ns2", "smtp", "secure", "vpn", "m", "shop",
+	"ftp", "mail2", "test", "portal", "ns", "ww1", "host",
+	"support", "dev", "web", "bbs", "ww42", "mx", "email",
+	"cloud", "1", "mail1", "2", "forum", "owa", "www2",
+	"gw", "admin", "store", "mx1", "cdn", "api", "exchange",
+	"app", "gov", "2020", "news",
+}
+
+// Words combined with existing labels when generating permutations
+var alterations = []string{
+	"dev", "test", "stage", "staging", "prod", "qa", "uat",
+	"api", "admin", "internal", "new", "old", "beta", "v1", "v2",
+}
+
+// maxPermutations caps the guesses generated from known names
+const maxPermutations = 5000
+
+// LoadWordlist reads one label per line, skipping blanks and # comments
+func LoadWordlist(filename string) ([]string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var words []string
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		w := strings.ToLower(strings.TrimSpace(scanner.Text()))
+		if w == "" || strings.HasPrefix(w, "#") || seen[w] {
+			continue
+		}
+		seen[w] = true
+		words = append(words, w)
+	}
+	return words, scanner.Err()
+}
+
+func withRoot(words []string, rootDomain string) []string {
+	names := make([]string, 0, len(words))
+	for _, w := range words {
+		names = append(names, w+"."+rootDomain)
+	}
+	return names
+}
+
+// detectWildcard resolves a few random labels under rootDomain. Anything
+// they resolve to is a wildcard answer; the returned set holds those
+// addresses (empty if the zone has no wildcard).
+func (s *SubdomainModule) detectWildcard(ctx context.Context, rootDomain string) map[string]bool {
+	wildcard := make(map[string]bool)
+	for i := 0; i < 3; i++ {
+		buf := make([]byte, 8)
+		rand.Read(buf)
+		probe := hex.EncodeToString(buf) + "." + rootDomain
+		if addrs, err := s.Resolver.LookupHost(ctx, probe); err == nil {
+			for _, a := range addrs {
+				wildcard[a] = true
+			}
+		}
+	}
+	if len(wildcard) > 0 {
+		s.Brain.Logf("[Subdomain] ⚠️ Wildcard DNS detected on *.%s (%d addresses). Guesses resolving only there are ignored.", rootDomain, len(wildcard))
+	}
+	return wildcard
+}
+
+// bruteForce resolves every candidate name and returns those that exist,
+// ignoring names that only resolve to wildcard addresses
+func (s *SubdomainModule) bruteForce(ctx context.Context, candidates []string, wildcard map[string]bool) []string {
+	var found []string
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	// Limit concurrency to avoid getting banned by ISP DNS
+	sem := make(chan struct{}, 20)
+
+	for _, candidate := range candidates {
+		acquired := false
+		select {
+		case sem <- struct{}{}:
+			acquired = true
+		case <-ctx.Done():
+		}
+		if !acquired {
+			break
+		}
+
+		wg.Add(1)
+		go func(t string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			addrs, err := s.Resolver.LookupHost(ctx, t)
+			if err != nil || onlyWildcard(addrs, wildcard) {
+				return
+			}
+			// It exists!
+			mu.Lock()
+			found = append(found, t)
+			mu.Unlock()
+			s.Brain.Logf("[Active] Discovered: %s", t)
+		}(candidate)
+	}
+	wg.Wait()
+	return found
+}
+
+func onlyWildcard(addrs []string, wildcard map[string]bool) bool {
+	if len(wildcard) == 0 {
+		return false
+	}
+	for _, a := range addrs {
+		if !wildcard[a] {
+			return false
+		}
+	}
+	return true
+}
+
+// permutations generates likely sibling names from known subdomains:
+// dev-api, api-dev, devapi, dev.api, and numbered siblings (api -> api1,
+// api2; api2 -> api1, api3, api4)
+func permutations(known []string, rootDomain string) []string {
+	knownSet := make(map[string]bool)
+	for _, k := range known {
+		knownSet[k] = true
+	}
+	guesses := make(map[string]bool)
+	add := func(label string) {
+		name := label + "." + rootDomain
+		if !knownSet[name] {
+			guesses[name] = true
+		}
+	}
+
+	for _, k := range known {
+		label := strings.TrimSuffix(k, "."+rootDomain)
+		if label == k || label == "" {
+			continue
+		}
+		// Only vary the leftmost label; keep the rest ("a.b" -> "dev-a.b")
+		first, rest := label, ""
+		if i := strings.Index(label, "."); i >= 0 {
+			first, rest = label[:i], label[i:]
+		}
+
+		for _, w := range alterations {
+			if w == first {
+				continue
+			}
+			add(w + "-" + first + rest)
+			add(first + "-" + w + rest)
+			add(w + first + rest)
+			add(w + "." + first + rest)
+		}
+
+		// Numbered siblings
+		stem := strings.TrimRight(first, "0123456789")
+		n, _ := strconv.Atoi(first[len(stem):])
+		if stem == "" {
+			continue
+		}
+		for _, d := range []int{n - 1, n + 1, n + 2} {
+			if d > 0 {
+				add(stem + strconv.Itoa(d) + rest)
+			}
+		}
+	}
+
+	list := make([]string, 0, len(guesses))
+	for g := range guesses {
+		list = append(list, g)
+	}
+	sort.Strings(list)
+	if len(list) > maxPermutations {
+		list = list[:maxPermutations]
+	}
+	return list
+}
@@ -0,0 +1,73 @@
+package modules
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+	"time"
+
+	"gorecTool/internal/engine"
+)
+
+// newDialer returns the dialer every module connects with. Its Control hook
+// runs after name resolution, on the address actually dialed, and refuses
+// the connection if the scope excludes that address: an in-scope hostname
+// may well resolve to an IP we must not touch.
+func newDialer(brain *engine.DecisionEngine, timeout time.Duration) *net.Dialer {
+	return &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				host = address
+			}
+			if brain.Excluded(host) {
+				return fmt.Errorf("%s is excluded by the scope", host)
+			}
+			return nil
+		},
+	}
+}
+
+// newTransport is an HTTP transport that dials through newDialer and
+// doesn't verify certificates (we want to see what answers, not trust it)
+func newTransport(brain *engine.DecisionEngine) *http.Transport {
+	return &http.Transport{
+		DialContext:     newDialer(brain, 0).DialContext,
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	}
+}
+
+// mayFollow decides whether a redirect from host to next is followed:
+// always on the same host, to another host only if a scope file says it
+// is ours. Excluded hosts are never followed.
+func mayFollow(brain *engine.DecisionEngine, host, next string) bool {
+	if brain.Excluded(next) {
+		return false
+	}
+	if strings.EqualFold(host, next) {
+		return true
+	}
+	return brain.HasScope() && brain.InScope(next)
+}
+
+// pinHost makes t connect to ip whenever a request goes to host, on any
+// port, so HTTP modules talk to the address the port scan found open
+// rather than whatever host resolves to now. The Host header and SNI still
+// carry the name. An empty ip leaves t alone.
+func pinHost(t *http.Transport, host, ip string) {
+	if ip == "" {
+		return
+	}
+	dial := t.DialContext
+	t.DialContext = func(ctx context.Context, network, address string) (net.Conn, error) {
+		if h, port, err := net.SplitHostPort(address); err == nil && strings.EqualFold(h, host) {
+			address = net.JoinHostPort(ip, port)
+		}
+		return dial(ctx, network, address)
+	}
+}
@@ -1,9 +1,11 @@
 package modules
 
 import (
+	"context"
 	"fmt"
 	"gorecTool/internal/engine"
 	"net/http"
+	"net/url"
 	"strings"
 	"time"
 )
@@ -16,36 +18,69 @@ func NewFileHunter(brain *engine.DecisionEngine) *FileHunter {
 	return &FileHunter{Brain: brain}
 }
 
-// Hunt picks the right wordlist based on the detected technology
-func (f *FileHunter) Hunt(target string, port int, techStack string) {
-	baseURL := fmt.Sprintf("http://%s:%d", target, port)
-	if port == 443 || port == 8443 {
-		baseURL = fmt.Sprintf("https://%s:%d", target, port)
+// Hunt picks the right wordlist based on the detected technology and
+// requests it under baseURL, the application root HttpAnalyzer found
+// (e.g. https://host:8443/app). ip, if set, is dialed for target, as in
+// HttpAnalyzer.Analyze.
+func (f *FileHunter) Hunt(ctx context.Context, target, ip string, port int, baseURL string, techStack []string) {
+	if !allowed(f.Brain, "file hunting", target) {
+		return
+	}
+	// baseURL may point elsewhere than target: check the host we will hit
+	base, err := url.Parse(baseURL)
+	if err != nil {
+		return
+	}
+	if host := base.Hostname(); !strings.EqualFold(host, target) && !allowed(f.Brain, "file hunting", host) {
+		return
+	}
+	if f.Brain.Excluded(base.Hostname()) {
+		f.Brain.Logf("[Scope] %s is excluded, file hunting skipped.", base.Hostname())
+		return
 	}
 
-	fmt.Printf("    >>> [HUNTER] Starting context-scan on %s (Tech: %s)\n", baseURL, techStack)
+	f.Brain.Logf("    >>> [HUNTER] Starting context-scan on %s (Tech: %s)", baseURL, strings.Join(techStack, ", "))
 
 	// 1. Define Context-Aware Wordlists
 	// Always check these generic sensitive files
 	files := []string{"robots.txt", ".env", ".git/HEAD", "sitemap.xml"}
 
 	// If we detected specific tech, add specific checks
-	if strings.Contains(techStack, "Apache") {
-		files = append(files, ".htaccess", "server-status")
-	}
-	if strings.Contains(techStack, "WordPress") {
-		files = append(files, "wp-config.php.bak", "wp-admin/admin-ajax.php")
-	}
-	if strings.Contains(techStack, "Nginx") {
-		files = append(files, "nginx.conf")
+	for _, tech := range techStack {
+		switch tech {
+		case "Apache":
+			files = append(files, ".htaccess", "server-status")
+		case "WordPress":
+			files = append(files, "wp-config.php.bak", "wp-admin/admin-ajax.php")
+		case "Nginx":
+			files = append(files, "nginx.conf")
+		}
 	}
 
 	// 2. Execute the Checks
-	client := &http.Client{Timeout: 3 * time.Second}
+	// Redirects are not followed: a login page answering 200 for every
+	// path is not a sensitive file
+	transport := newTransport(f.Brain)
+	pinHost(transport, target, ip)
+	client := &http.Client{
+		Transport: transport,
+		Timeout:   3 * time.Second,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
 
 	for _, file := range files {
+		if ctx.Err() != nil {
+			return
+		}
+
 		url := fmt.Sprintf("%s/%s", baseURL, file)
-		resp, err := client.Get(url)
+		req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+		if err != nil {
+			continue
+		}
+		resp, err := client.Do(req)
 
 		if err != nil {
 			continue
@@ -54,15 +89,35 @@ func (f *FileHunter) Hunt(target string, port int, techStack string) {
 		// 3. Analyze Response
 		// We only care if it exists (200 OK) and isn't a fake custom 404 page
 		if resp.StatusCode == 200 {
-			fmt.Printf("    >>> [!] ALERT: Found Sensitive File: %s\n", url)
+			f.Brain.Logf("    >>> [!] ALERT: Found Sensitive File: %s", url)
 
 			// Feed back to Brain (Could trigger a downloader module)
 			f.Brain.Publish(engine.Event{
-				Type:    engine.EventVulnFound,
-				Target:  target,
-				Payload: fmt.Sprintf("Sensitive File: %s", file),
+				Type:   engine.EventVulnFound,
+				Target: target,
+				Payload: engine.VulnFound{
+					Name:     "Sensitive File",
+					Severity: fileSeverity(file),
+					Port:     port,
+					URL:      url,
+					Evidence: fmt.Sprintf("GET %s returned %d", url, resp.StatusCode),
+				},
 			})
 		}
 		resp.Body.Close()
 	}
 }
+
+// fileSeverity ranks how bad it is that a given file is reachable
+func fileSeverity(file string) string {
+	switch file {
+	case ".env", ".git/HEAD", "wp-config.php.bak":
+		return engine.SeverityHigh
+	case ".htaccess", "server-status", "nginx.conf":
+		return engine.SeverityMedium
+	case "wp-admin/admin-ajax.php":
+		return engine.SeverityLow
+	default:
+		return engine.SeverityInfo
+	}
+}
@@ -1,75 +1,167 @@
 package modules
 
 import (
-	"crypto/tls"
+	"context"
 	"fmt"
 	"gorecTool/internal/engine"
 	"io"
+	"net"
 	"net/http"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
 type HttpAnalyzer struct {
 	Brain *engine.DecisionEngine
+
+	mu   sync.Mutex
+	seen map[string]bool // host|ip:port already analyzed
 }
 
 func NewHttpAnalyzer(brain *engine.DecisionEngine) *HttpAnalyzer {
-	return &HttpAnalyzer{Brain: brain}
+	return &HttpAnalyzer{Brain: brain, seen: make(map[string]bool)}
 }
 
-// Analyze is triggered when a Web Port (80, 443, 8080) is found
-func (h *HttpAnalyzer) Analyze(target string, port int) {
-	// Construct the URL.
-	// Simple logic: If 443, assume HTTPS. Else try HTTP first.
-	protocol := "http"
-	if port == 443 || port == 8443 {
-		protocol = "https"
+// maxRedirects caps how far Analyze follows a redirect chain
+const maxRedirects = 10
+
+// Analyze is triggered when a web server is found. It tries both http and
+// https on the port (scheme, if known, first; otherwise https first on
+// 443/8443), follows redirects and publishes what answered, chain included.
+// ip, if set, is the address the port was found open on and is the one
+// dialed. Each host, address and port is analyzed once, so a dual-stack
+// host gets one analysis per family.
+func (h *HttpAnalyzer) Analyze(ctx context.Context, target, ip string, port int, scheme string) {
+	if !allowed(h.Brain, "HTTP analysis", target) {
+		return
+	}
+	key := strings.ToLower(target) + "|" + net.JoinHostPort(ip, strconv.Itoa(port))
+	h.mu.Lock()
+	done := h.seen[key]
+	h.seen[key] = true
+	h.mu.Unlock()
+	if done {
+		return
+	}
+	schemes := []string{"http", "https"}
+	if scheme == "https" || (scheme == "" && (port == 443 || port == 8443)) {
+		schemes = []string{"https", "http"}
+	}
+
+	var found []engine.HttpService
+	for _, protocol := range schemes {
+		url := fmt.Sprintf("%s://%s:%d", protocol, target, port)
+		h.Brain.Logf("    >>> [HTTP] Analyzing %s...", url)
+		svc, err := h.fetch(ctx, url, target, ip, port)
+		if err != nil {
+			if ctx.Err() == nil {
+				h.Brain.Logf("    >>> [HTTP] %s: %v", url, err)
+			}
+			continue
+		}
+		found = append(found, svc)
+	}
+	if len(found) == 0 {
+		return
 	}
-	url := fmt.Sprintf("%s://%s:%d", protocol, target, port)
 
-	fmt.Printf("    >>> [HTTP] Analyzing %s...\n", url)
+	// http://host redirecting to https://host/ and https://host itself are
+	// the same application: keep the probe that saw the upgrade
+	sort.SliceStable(found, func(i, j int) bool { return found[i].HTTPSUpgrade && !found[j].HTTPSUpgrade })
+	seen := make(map[string]bool)
+	for _, svc := range found {
+		if seen[svc.FinalURL] {
+			continue
+		}
+		seen[svc.FinalURL] = true
+
+		h.Brain.Logf("    >>> [HTTP] [%d] %s | Title: %q | Server: %s | Tech: %s",
+			svc.StatusCode, strings.Join(append(svc.Redirects, svc.FinalURL), " -> "), svc.Title, svc.Server, strings.Join(svc.Tech, ", "))
 
-	// 1. Setup Client (Ignore bad SSL certs)
-	tr := &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		// Feed the Brain (For future exploits)
+		h.Brain.Publish(engine.Event{
+			Type:    engine.EventHttpService,
+			Target:  target,
+			Payload: svc,
+		})
 	}
+}
+
+// fetch GETs url, following redirects as long as mayFollow allows, and
+// fingerprints the page it ends up on
+func (h *HttpAnalyzer) fetch(ctx context.Context, url, target, ip string, port int) (engine.HttpService, error) {
+	// 1. Setup Client (Ignore bad SSL certs, record every redirect)
+	var chain []string
+	transport := newTransport(h.Brain)
+	pinHost(transport, target, ip)
 	client := &http.Client{
-		Transport: tr,
+		Transport: transport,
 		Timeout:   5 * time.Second,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			chain = append(chain, via[len(via)-1].URL.String())
+			if len(via) >= maxRedirects {
+				return http.ErrUseLastResponse
+			}
+			if !mayFollow(h.Brain, via[0].URL.Hostname(), req.URL.Hostname()) {
+				h.Brain.Logf("    >>> [HTTP] Not following redirect to %s (other host, not in scope)", req.URL)
+				return http.ErrUseLastResponse
+			}
+			return nil
+		},
 	}
 
 	// 2. Fetch the Page
-	resp, err := client.Get(url)
-	// fmt.Printf("here%v %v", resp, err)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
-		// If HTTP fails on port 80, maybe it redirects to HTTPS?
-		// For now, we just log the error.
-		return
+		return engine.HttpService{}, err
+	}
+	resp, err := client.Do(req)
+	if err != nil && len(chain) > 0 && ctx.Err() == nil {
+		// A redirect led nowhere: report the redirect itself instead
+		h.Brain.Logf("    >>> [HTTP] %s: redirect failed (%v), keeping the first response", url, err)
+		chain = nil
+		client.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+		if req, err = http.NewRequestWithContext(ctx, "GET", url, nil); err != nil {
+			return engine.HttpService{}, err
+		}
+		resp, err = client.Do(req)
+	}
+	if err != nil {
+		return engine.HttpService{}, err
 	}
 	defer resp.Body.Close()
 
 	// 3. Read Body (First 4KB is usually enough for title/headers)
-
 	bodyBytes, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
 	bodyStr := string(bodyBytes)
-	// fmt.Println(bodyBytes, bodyStr)
+	if resp.StatusCode == http.StatusBadRequest && plainToTLS.MatchString(bodyStr) {
+		// A TLS port's "you spoke plain HTTP" page, not an application
+		return engine.HttpService{}, fmt.Errorf("port expects TLS")
+	}
+
 	// 4. Extract Data
-	title := extractTitle(bodyStr)
-	server := resp.Header.Get("Server")
-	tech := detectTech(resp.Header, bodyStr)
-	fmt.Println(title, server, tech)
-	// 5. Report Findings
-	fmt.Printf("    >>> [HTTP] [%d] Title: %q | Server: %s | Tech: %s\n",
-		resp.StatusCode, title, server, tech)
-
-	// 6. Feed the Brain (For future exploits)
-	h.Brain.Publish(engine.Event{
-		Type:    engine.EventHttpService,
-		Target:  target,
-		Payload: fmt.Sprintf("%s|%s|%d", server, tech, port),
-	})
+	final := resp.Request.URL
+	if n := len(chain); n > 0 && chain[n-1] == final.String() {
+		chain = chain[:n-1] // Redirect we chose not to follow: it answered last
+	}
+	svc := engine.HttpService{
+		Port:       port,
+		Scheme:     final.Scheme,
+		URL:        url,
+		StatusCode: resp.StatusCode,
+		Title:      extractTitle(bodyStr),
+		Server:     resp.Header.Get("Server"),
+		Tech:       detectTech(resp.Header, bodyStr),
+		Redirects:  chain,
+		FinalURL:   final.String(),
+		IP:         ip,
+	}
+	svc.HTTPSUpgrade = strings.HasPrefix(url, "http://") && final.Scheme == "https"
+	return svc, nil
 }
 
 // Helper: Extract <title>...</title>
@@ -83,8 +175,8 @@ func extractTitle(body string) string {
 }
 
 // Helper: Simple Technology Fingerprinting
-func detectTech(headers http.Header, body string) string {
-	var detected []string
+func detectTech(headers http.Header, body string) []string {
+	detected := []string{} // Never nil, so JSON output always has a list
 
 	// Check Headers
 	if strings.Contains(headers.Get("X-Powered-By"), "PHP") {
@@ -105,8 +197,5 @@ func detectTech(headers http.Header, body string) string {
 		detected = append(detected, "React/Next.js")
 	}
 
-	if len(detected) == 0 {
-		return "Unknown"
-	}
-	return strings.Join(detected, ", ")
+	return detected
 }
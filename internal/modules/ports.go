@@ -0,0 +1,142 @@
+package modules
+
+import (
+	_ "embed"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//go:embed top_ports.txt
+var topPortsFile string
+
+// DefaultPorts is the "Top 20" list used by quick scans when no ports are
+// given, to keep the "Scout" phase fast
+var DefaultPorts = []int{21, 22, 23, 25, 53, 80, 110, 111, 135, 139,
+	143, 443, 445, 993, 995, 1723, 3306, 3389, 5900, 8080}
+
+// Profiles are named port lists that can be used in a port spec
+var Profiles = map[string][]int{
+	"web": {80, 81, 443, 591, 3000, 4443, 5000, 8000, 8008, 8080, 8081,
+		8088, 8443, 8888, 9000, 9090, 9443},
+	"database": {1433, 1521, 3306, 5432, 5984, 6379, 7000, 7001, 8086,
+		9042, 9200, 9300, 11211, 27017, 27018, 28017},
+	"remote-admin": {22, 23, 512, 513, 514, 2222, 2375, 2376, 3389, 5900,
+		5901, 5985, 5986, 6443, 10250},
+}
+
+// AllPorts is every TCP port (what a deep scan covers)
+func AllPorts() []int {
+	ports := make([]int, 0, 65535)
+	for i := 1; i <= 65535; i++ {
+		ports = append(ports, i)
+	}
+	return ports
+}
+
+// rankedPorts is the embedded ranking, most commonly open first
+var rankedPorts = parseRanking(topPortsFile)
+
+// MaxTopPorts is the largest n TopPorts can serve: the length of the
+// embedded ranking
+var MaxTopPorts = len(rankedPorts)
+
+// TopPorts returns the n most commonly open ports, most common first. n is
+// capped at MaxTopPorts; callers should reject larger values.
+func TopPorts(n int) []int {
+	n = min(n, MaxTopPorts)
+	return append([]int(nil), rankedPorts[:n]...)
+}
+
+func parseRanking(file string) []int {
+	var ports []int
+	for _, line := range strings.Split(file, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if p, err := strconv.Atoi(line); err == nil {
+			ports = append(ports, p)
+		}
+	}
+	return ports
+}
+
+// ParsePorts turns a spec like "22,80,8000-9000" or "web,3306" into a
+// sorted list of unique ports. Entries are single ports, ranges or names
+// from Profiles.
+func ParsePorts(spec string) ([]int, error) {
+	seen := make(map[int]bool)
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.ToLower(strings.TrimSpace(part))
+		if part == "" {
+			continue
+		}
+		if profile, ok := Profiles[part]; ok {
+			for _, p := range profile {
+				seen[p] = true
+			}
+			continue
+		}
+
+		lo, hi, isRange := strings.Cut(part, "-")
+		first, err := parsePort(lo)
+		if err != nil {
+			return nil, err
+		}
+		last := first
+		if isRange {
+			if last, err = parsePort(hi); err != nil {
+				return nil, err
+			}
+			if last < first {
+				return nil, fmt.Errorf("bad port range %q", part)
+			}
+		}
+		for p := first; p <= last; p++ {
+			seen[p] = true
+		}
+	}
+	if len(seen) == 0 {
+		return nil, fmt.Errorf("no ports in %q", spec)
+	}
+	return sortedPorts(seen), nil
+}
+
+func parsePort(s string) (int, error) {
+	p, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || p < 1 || p > 65535 {
+		return 0, fmt.Errorf("bad port %q (want 1-65535 or one of %s)", s, profileNames())
+	}
+	return p, nil
+}
+
+// MergePorts returns the sorted union of the given port lists
+func MergePorts(lists ...[]int) []int {
+	seen := make(map[int]bool)
+	for _, l := range lists {
+		for _, p := range l {
+			seen[p] = true
+		}
+	}
+	return sortedPorts(seen)
+}
+
+func sortedPorts(set map[int]bool) []int {
+	ports := make([]int, 0, len(set))
+	for p := range set {
+		ports = append(ports, p)
+	}
+	sort.Ints(ports)
+	return ports
+}
+
+func profileNames() string {
+	names := make([]string, 0, len(Profiles))
+	for n := range Profiles {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
@@ -1,103 +1,391 @@
 package modules
 
 import (
+	"context"
 	"fmt"
 	"net"
 	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	// Import your engine package
 	// You might need to adjust this path based on your go.mod name
 	"gorecTool/internal/engine"
+	"gorecTool/internal/ratelimit"
+	"gorecTool/internal/resolver"
 )
 
 type PortScanner struct {
 	Brain *engine.DecisionEngine
+
+	// Limiter is an optional semaphore shared by every ScanTarget call so the
+	// total number of dials stays bounded across targets. When nil each scan
+	// gets its own (100 for short lists, 2000 for large ones).
+	Limiter chan struct{}
+
+	// Ports are scanned when a caller passes no list (DefaultPorts if nil)
+	Ports []int
+
+	// UDPPorts are scanned by ScanGroupUDP when a caller passes no list
+	// (DefaultUDPPorts if nil)
+	UDPPorts []int
+
+	// RateLimit, if set, caps connection attempts per second across every
+	// scan of this scanner (--rate)
+	RateLimit *ratelimit.Limiter
+
+	// ReportStates also publishes a PORT_STATE event for every closed and
+	// filtered port (off by default: a deep scan has tens of thousands)
+	ReportStates bool
+
+	backoff backoff
+
+	// OnProgress, if set, is called with the number of ports finished since
+	// the previous call (in batches of 50, plus the remainder at the end).
+	OnProgress func(scanned int)
+
+	// Resolver maps hostnames to addresses for Plan
+	Resolver resolver.Resolver
+
+	// Family picks the addresses Plan scans: FamilyV4 (the default when
+	// empty), FamilyV6 or FamilyBoth
+	Family string
 }
 
+// Address family choices for PortScanner.Family (--ip-family)
+const (
+	FamilyV4   = "v4"
+	FamilyV6   = "v6"
+	FamilyBoth = "both"
+)
+
+// Families lists the valid PortScanner.Family values
+var Families = []string{FamilyV4, FamilyV6, FamilyBoth}
+
 func NewPortScanner(brain *engine.DecisionEngine) *PortScanner {
 	return &PortScanner{
-		Brain: brain,
+		Brain:    brain,
+		Resolver: resolver.System{},
+	}
+}
+
+// HostGroup is one address and every hostname that resolves to it
+type HostGroup struct {
+	Address string
+	Hosts   []string
+}
+
+// Plan resolves the hosts and groups them by address, so hosts behind the
+// same load balancer are scanned once. Depending on ps.Family a host joins
+// the group of its first IPv4 address, its first IPv6 address, or both.
+// Hosts that don't resolve and hosts without an address of the wanted
+// family are logged and skipped: there is nothing to scan, and counting
+// their DNS failures as filtered ports would be a lie. Groups keep the
+// order of hosts.
+func (ps *PortScanner) Plan(ctx context.Context, hosts []string) []HostGroup {
+	var groups []HostGroup
+	index := make(map[string]int)
+	for _, h := range hosts {
+		addrs := []string{h}
+		if net.ParseIP(h) == nil {
+			resolved, err := ps.Resolver.LookupHost(ctx, h)
+			if err != nil {
+				if ctx.Err() == nil {
+					ps.Brain.Logf("[Scanner] Skipping %s: does not resolve (%v)", h, err)
+				}
+				continue
+			}
+			addrs = resolved
+		}
+		picked := ps.pickAddresses(addrs)
+		if len(picked) == 0 {
+			ps.Brain.Logf("[Scanner] Skipping %s: no %s address (see --ip-family)", h, ps.familyName())
+			continue
+		}
+		for _, addr := range picked {
+			if i, ok := index[addr]; ok {
+				groups[i].Hosts = append(groups[i].Hosts, h)
+				continue
+			}
+			index[addr] = len(groups)
+			groups = append(groups, HostGroup{Address: addr, Hosts: []string{h}})
+		}
+	}
+	return groups
+}
+
+// pickAddresses returns the first IPv4 and/or IPv6 address, as ps.Family asks
+func (ps *PortScanner) pickAddresses(addrs []string) []string {
+	var v4, v6 string
+	for _, a := range addrs {
+		ip := net.ParseIP(a)
+		switch {
+		case ip == nil:
+		case ip.To4() != nil:
+			if v4 == "" {
+				v4 = a
+			}
+		case v6 == "":
+			v6 = a
+		}
+	}
+	var picked []string
+	if v4 != "" && ps.Family != FamilyV6 {
+		picked = append(picked, v4)
+	}
+	if v6 != "" && (ps.Family == FamilyV6 || ps.Family == FamilyBoth) {
+		picked = append(picked, v6)
+	}
+	return picked
+}
+
+func (ps *PortScanner) familyName() string {
+	switch ps.Family {
+	case FamilyV6:
+		return "IPv6"
+	case FamilyBoth:
+		return "IP"
 	}
+	return "IPv4"
 }
 
-// ScanTarget is the entry point. It scans common ports on a single target.
-func (ps *PortScanner) ScanTarget(target string, deep bool) {
-
-	// A list of "Top 20" critical ports to keep it fast for the "Scout" phase
-	var ports []int
-	var concurrency int
-	if deep {
-		fmt.Printf("[Scanner] Starting DEEP scan on %s (1-65535)...\n", target)
-		// Generate full range
-		for i := 1; i <= 65535; i++ {
-			ports = append(ports, i)
+// network picks the dial network for an address: the family of an IP, or
+// the configured family for a hostname
+func (ps *PortScanner) network(protocol, address string) string {
+	if ip := net.ParseIP(address); ip != nil {
+		if ip.To4() != nil {
+			return protocol + "4"
 		}
+		return protocol + "6"
+	}
+	switch ps.Family {
+	case FamilyV6:
+		return protocol + "6"
+	case FamilyBoth:
+		return protocol
+	}
+	// Only IPv4 by default: this prevents Go from also trying IPv6,
+	// cutting socket usage in half.
+	return protocol + "4"
+}
+
+// ScanTarget is the entry point. It scans the given ports on a single target
+// (ps.Ports when empty), once per address family in ps.Family. It stops
+// launching new dials as soon as ctx is cancelled.
+func (ps *PortScanner) ScanTarget(ctx context.Context, target string, ports []int) {
+	for _, group := range ps.Plan(ctx, []string{target}) {
+		ps.ScanGroup(ctx, group, ports)
+	}
+}
+
+// ScanTargetUDP is ScanTarget for UDP ports (ps.UDPPorts when empty)
+func (ps *PortScanner) ScanTargetUDP(ctx context.Context, target string, ports []int) {
+	for _, group := range ps.Plan(ctx, []string{target}) {
+		ps.ScanGroupUDP(ctx, group, ports)
+	}
+}
+
+// ScanGroup scans the group's address once and publishes every open port
+// for each of its hostnames, so per-host rules (HTTP analysis with the
+// right Host header) still run for all of them.
+func (ps *PortScanner) ScanGroup(ctx context.Context, group HostGroup, ports []int) {
+	if len(ports) == 0 {
+		ports = ps.Ports
+	}
+	if len(ports) == 0 {
+		ports = DefaultPorts
+	}
+	ps.scan(ctx, group, ports, "tcp")
+}
+
+// ScanGroupUDP is ScanGroup for UDP ports (ps.UDPPorts when empty). Only
+// ports that answered a probe are published as PORT_OPEN; silent ones are
+// open|filtered and only show up as PORT_STATE events and in the summary.
+func (ps *PortScanner) ScanGroupUDP(ctx context.Context, group HostGroup, ports []int) {
+	if len(ports) == 0 {
+		ports = ps.UDPPorts
+	}
+	if len(ports) == 0 {
+		ports = DefaultUDPPorts
+	}
+	ps.scan(ctx, group, ports, "udp")
+}
+
+func (ps *PortScanner) scan(ctx context.Context, group HostGroup, ports []int, protocol string) {
+	// Only scan for the hosts in scope, and never an excluded address
+	var hosts []string
+	for _, h := range group.Hosts {
+		if allowed(ps.Brain, "port scan", h) {
+			hosts = append(hosts, h)
+		}
+	}
+	if len(hosts) == 0 {
+		return
+	}
+	if ps.Brain.Excluded(group.Address) {
+		ps.Brain.Logf("[Scope] %s is excluded, port scan of %s skipped.", group.Address, strings.Join(hosts, ", "))
+		return
+	}
+	group.Hosts = hosts
+
+	target := group.Address
+	if len(group.Hosts) > 1 {
+		target = fmt.Sprintf("%s (%s)", group.Address, strings.Join(group.Hosts, ", "))
+	}
+	probe := ps.probe
+	if protocol == "udp" {
+		probe = ps.probeUDP
+	}
+
+	concurrency := 100
+	if len(ports) > 1000 {
 		concurrency = 2000
-	} else {
-		fmt.Printf("[Scanner] Starting QUICK scan on %s (Top 20)...\n", target)
-		// Your existing Top 20 list
-		ports = []int{21, 22, 23, 25, 53, 80, 110, 111, 135, 139,
-			143, 443, 445, 993, 995, 1723, 3306, 3389, 5900, 8080}
-		concurrency = 100
+	}
+	ps.Brain.Logf("[Scanner] Starting %s scan on %s (%d ports)...", protocol, target, len(ports))
+
+	// Semaphore to control concurrency
+	// This prevents your OS from running out of file descriptors
+	sem := ps.Limiter
+	if sem == nil {
+		sem = make(chan struct{}, concurrency)
 	}
 
 	var wg sync.WaitGroup
+	// We use a local atomic counter to batch progress updates safely from threads
+	var localProgress int32 = 0
+	// Dial timeouts follow this host's measured round trip time
+	rtt := &rttEstimator{}
+	var open, closed, filtered int32
+	ip, family := "", ""
+	switch ps.network("tcp", group.Address) {
+	case "tcp4":
+		family = engine.FamilyIPv4
+	case "tcp6":
+		family = engine.FamilyIPv6
+	}
+	if net.ParseIP(group.Address) != nil {
+		ip = group.Address
+	}
+	publish := func(typ engine.EventType, payload engine.Payload) {
+		for _, host := range group.Hosts {
+			ps.Brain.Publish(engine.Event{Type: typ, Target: host, Payload: payload})
+		}
+	}
 
-	// Semaphore to control concurrency (limit to 10 threads at once)
-	// This prevents your OS from running out of file descriptors
-	sem := make(chan struct{}, concurrency)
+	for i, port := range ports {
+		if ps.RateLimit.Wait(ctx) != nil {
+			break
+		}
+		// Acquire token before spawning, so a deep scan doesn't park 65k goroutines
+		acquired := false
+		select {
+		case sem <- struct{}{}:
+			acquired = true
+		case <-ctx.Done():
+		}
+		if !acquired {
+			break
+		}
 
-	for _, port := range ports {
 		wg.Add(1)
-
-		// Launch a goroutine for every port
 		go func(p int) {
 			defer wg.Done()
 
-			// Acquire token (block if full)
-			sem <- struct{}{}
-			// We define a separate function for the scan so we can defer the release properly
-			// This ensures 'sem' is released even if Publish blocks
-			// defer func() { <-sem }() // Release token IMMEDIATELY when this inner func finishes
-
-			open := ps.isOpen(target, p)
+			state := probe(ctx, group.Address, p, rtt)
 
 			// RELEASE TOKEN IMMEDIATELY
 			<-sem
-			if open {
+			// The token is already released, so a busy Brain only
+			// stalls this goroutine, never the rest of the scan.
+			switch state {
+			case engine.StateOpen:
+				atomic.AddInt32(&open, 1)
 				// CRITICAL: We don't just print, we tell the Brain!
+				ps.Brain.Logf("[+] Open: %d/%s on %s ", p, protocol, target)
+				publish(engine.EventPortOpen, engine.PortOpen{Port: p, Protocol: protocol, IP: ip, Family: family})
+			case engine.StateClosed, engine.StateFiltered, engine.StateOpenFiltered:
+				if state == engine.StateClosed {
+					atomic.AddInt32(&closed, 1)
+				} else {
+					atomic.AddInt32(&filtered, 1)
+				}
+				if ps.ReportStates {
+					publish(engine.EventPortState, engine.PortState{Port: p, Protocol: protocol, State: state, IP: ip, Family: family})
+				}
+			}
 
-				fmt.Printf("[+] Open: %d on %s \n", p, target)
-
-				// Tell the Brain
-				// CRITICAL FIX: Run Publish in a new goroutine.
-				// This prevents the "Scanner" from waiting on the "Brain".
-				// The scanner can now return, release the semaphore, and let the next port run.
-				go func() {
-					ps.Brain.Publish(engine.Event{
-						Type:    engine.EventPortOpen,
-						Target:  target,
-						Payload: fmt.Sprintf("%d", p),
-					})
-				}()
+			if ps.OnProgress != nil && atomic.AddInt32(&localProgress, 1)%50 == 0 {
+				ps.OnProgress(50)
 			}
 		}(port)
+
+		// Micro-Sleep every 100 ports (unless --rate already paces us)
+		// This gives the OS (Windows in particular) time to recycle "TIME_WAIT" sockets
+		if ps.RateLimit == nil && i%100 == 0 {
+			time.Sleep(10 * time.Millisecond)
+		}
 	}
 
 	wg.Wait()
-	fmt.Printf("[Scanner] Finished scanning %s.\n", target)
+	if ctx.Err() != nil {
+		ps.Brain.Logf("[Scanner] Scan of %s cancelled.", target)
+		return
+	}
+	// Flush any remaining progress count (e.g., last 34 ports)
+	if rem := atomic.LoadInt32(&localProgress) % 50; ps.OnProgress != nil && rem > 0 {
+		ps.OnProgress(int(rem))
+	}
+
+	summary := engine.PortScanSummary{
+		Protocol: protocol,
+		IP:       ip,
+		Family:   family,
+		Scanned:  int(open + closed + filtered),
+		Open:     int(open),
+		Closed:   int(closed),
+		Filtered: int(filtered),
+	}
+	publish(engine.EventPortSummary, summary)
+	ps.Brain.Logf("[Scanner] Finished scanning %s: %s", target, summary)
 }
 
-// isOpen tries to connect to the port
-func (ps *PortScanner) isOpen(target string, port int) bool {
-	// address := fmt.Sprintf("%s:%d", target, port)
+// probe tries to connect to the port and classifies the outcome: open on
+// a completed handshake, closed when refused, filtered on timeouts and
+// unreachable errors. The timeout adapts to the host's RTT. Every dial
+// waits out the scanner's backoff, and dials that fail because we ran out
+// of sockets are retried until one gets through: a port is never dropped
+// for our own lack of sockets. It returns "" if ctx was cancelled first.
+func (ps *PortScanner) probe(ctx context.Context, target string, port int, rtt *rttEstimator) string {
 	address := net.JoinHostPort(target, strconv.Itoa(port))
-	conn, err := net.DialTimeout("tcp", address, 1*time.Second) // 1s timeout
-	if err != nil {
-		return false
+
+	for {
+		if !ps.backoff.wait(ctx) {
+			return ""
+		}
+		dialer := newDialer(ps.Brain, rtt.timeout())
+		start := time.Now()
+		conn, err := dialer.DialContext(ctx, ps.network("tcp", target), address)
+		switch {
+		case err == nil:
+			rtt.observe(time.Since(start))
+			ps.backoff.succeed()
+			conn.Close()
+			return engine.StateOpen
+		case ctx.Err() != nil:
+			return ""
+		case isRefused(err):
+			rtt.observe(time.Since(start))
+			ps.backoff.succeed()
+			return engine.StateClosed
+		case !isResourceError(err):
+			// Timeouts, host/network unreachable, ...
+			return engine.StateFiltered
+		}
+
+		if ps.backoff.fail() {
+			ps.Brain.Logf("[Scanner] Out of sockets (%v), backing off...", err)
+		}
 	}
-	conn.Close()
-	return true
 }
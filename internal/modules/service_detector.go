@@ -0,0 +1,278 @@
+package modules
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorecTool/internal/engine"
+)
+
+// ServiceDetector identifies what listens on an open port: it first waits
+// for a banner (SSH, FTP, SMTP, MySQL greet the client), then sends probes
+// (Redis, HTTP, TLS) to services that wait for the client to speak.
+type ServiceDetector struct {
+	Brain *engine.DecisionEngine
+
+	// Timeout bounds each connection, and the wait for a banner
+	Timeout time.Duration
+}
+
+func NewServiceDetector(brain *engine.DecisionEngine) *ServiceDetector {
+	return &ServiceDetector{Brain: brain, Timeout: 3 * time.Second}
+}
+
+// Detect identifies the service on target:port and publishes a
+// SERVICE_DETECTED event. ip, if set, is the address the port was found
+// open on: it is dialed instead of resolving target again (which could
+// pick the other family), target still names the host for TLS and HTTP.
+// Ports nothing could be learned about are skipped.
+func (d *ServiceDetector) Detect(ctx context.Context, target, ip string, port int) {
+	if !allowed(d.Brain, "service detection", target) {
+		return
+	}
+	host := target
+	if ip != "" {
+		host = ip
+	}
+	svc, ok := d.identify(ctx, target, net.JoinHostPort(host, strconv.Itoa(port)))
+	if !ok {
+		d.Brain.Logf("    >>> [SERVICE] %s:%d did not answer any probe", host, port)
+		return
+	}
+	svc.Port, svc.Protocol, svc.IP, svc.Family = port, "tcp", ip, engine.FamilyOf(ip)
+	d.Brain.Logf("    >>> [SERVICE] %s: %s", target, svc)
+	d.Brain.Publish(engine.Event{Type: engine.EventServiceFound, Target: target, Payload: svc})
+}
+
+// identify probes address, presenting target as the host name
+func (d *ServiceDetector) identify(ctx context.Context, target, address string) (engine.ServiceDetected, bool) {
+	// 1. NULL probe: many services speak first
+	if banner, err := d.exchange(ctx, target, address, nil, false); err == nil && len(banner) > 0 {
+		if svc, ok := parseBanner(banner); ok {
+			return svc, true
+		}
+	}
+	if ctx.Err() != nil {
+		return engine.ServiceDetected{}, false
+	}
+
+	// 2. Redis answers a bare command (and hangs up on anything with a Host header)
+	if reply, err := d.exchange(ctx, target, address, []byte("INFO server\r\n"), false); err == nil {
+		if svc, ok := parseRedis(reply); ok {
+			return svc, true
+		}
+	}
+
+	// 3. Plain HTTP on any port
+	httpProbe := []byte(fmt.Sprintf("GET / HTTP/1.0\r\nHost: %s\r\nUser-Agent: %s\r\n\r\n", target, userAgent))
+	if reply, err := d.exchange(ctx, target, address, httpProbe, false); err == nil {
+		// HTTPS servers often answer plain HTTP with a 400 "use HTTPS"
+		// page; that port is really TLS, so fall through
+		if svc, ok := parseHTTP(reply); ok && !wantsTLS(reply) {
+			return svc, true
+		}
+	}
+
+	// 4. TLS, and HTTP inside it
+	reply, err := d.exchange(ctx, target, address, httpProbe, true)
+	if err != nil {
+		if _, isTLS := err.(tlsOnlyError); isTLS {
+			return engine.ServiceDetected{Service: "tls", TLS: true}, true
+		}
+		return engine.ServiceDetected{}, false
+	}
+	if svc, ok := parseHTTP(reply); ok {
+		svc.TLS = true
+		return svc, true
+	}
+	return engine.ServiceDetected{Service: "tls", TLS: true, Banner: firstLine(reply)}, true
+}
+
+// tlsOnlyError means the TLS handshake worked but the inner probe got nothing
+type tlsOnlyError struct{ error }
+
+// exchange connects to address, optionally sends probe, and reads what
+// comes back until the service goes quiet, closes, or 4KB arrived. TLS
+// connections send target as the server name.
+func (d *ServiceDetector) exchange(ctx context.Context, target, address string, probe []byte, useTLS bool) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, d.Timeout)
+	defer cancel()
+
+	conn, err := newDialer(d.Brain, 0).DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	deadline, _ := ctx.Deadline()
+	conn.SetDeadline(deadline)
+
+	if useTLS {
+		tlsConn := tls.Client(conn, &tls.Config{InsecureSkipVerify: true, ServerName: target})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			return nil, err
+		}
+		conn = tlsConn
+	}
+
+	if probe != nil {
+		if _, err := conn.Write(probe); err != nil {
+			return nil, err
+		}
+	}
+
+	buf := make([]byte, 4096)
+	n := 0
+	for n < len(buf) {
+		m, err := conn.Read(buf[n:])
+		n += m
+		if err != nil {
+			break
+		}
+		// Got something: give the rest a short moment, then stop
+		conn.SetReadDeadline(time.Now().Add(300 * time.Millisecond))
+	}
+	buf = buf[:n]
+	if len(buf) == 0 && useTLS {
+		return nil, tlsOnlyError{fmt.Errorf("no answer inside TLS")}
+	}
+	return buf, nil
+}
+
+var (
+	sshBanner   = regexp.MustCompile(`^SSH-[\d.]+-([A-Za-z][\w.-]*?)[_-]([\w.]+)`)
+	ftpProducts = []*regexp.Regexp{
+		regexp.MustCompile(`\((vsFTPd) ([\d.]+)\)`),
+		regexp.MustCompile(`(ProFTPD) ([\d.]+\w*)`),
+		regexp.MustCompile(`(Pure-FTPd)`),
+		regexp.MustCompile(`(FileZilla Server) (?:version )?([\d.]+\w*)`),
+		regexp.MustCompile(`(Microsoft FTP Service)`),
+	}
+	smtpProducts = []*regexp.Regexp{
+		regexp.MustCompile(`(Exim) ([\d.]+)`),
+		regexp.MustCompile(`(Postfix)`),
+		regexp.MustCompile(`(Sendmail) ([\w.]+)`),
+		regexp.MustCompile(`(Microsoft ESMTP MAIL Service)`),
+	}
+	serverHeader = regexp.MustCompile(`^([^/\s]+)(?:/(\S+))?`)
+)
+
+// parseBanner recognizes services that greet first
+func parseBanner(b []byte) (engine.ServiceDetected, bool) {
+	line := firstLine(b)
+	switch {
+	case strings.HasPrefix(line, "SSH-"):
+		svc := engine.ServiceDetected{Service: "ssh", Banner: line}
+		if m := sshBanner.FindStringSubmatch(line); m != nil {
+			svc.Product, svc.Version = m[1], m[2]
+		}
+		return svc, true
+	case strings.HasPrefix(line, "220"):
+		upper := strings.ToUpper(string(b))
+		if strings.Contains(upper, "SMTP") || strings.Contains(upper, "MAIL") {
+			return withProduct(engine.ServiceDetected{Service: "smtp", Banner: line}, smtpProducts, string(b)), true
+		}
+		// Most other "220 " greetings are FTP servers
+		return withProduct(engine.ServiceDetected{Service: "ftp", Banner: line}, ftpProducts, string(b)), true
+	}
+	if svc, ok := parseMySQL(b); ok {
+		return svc, true
+	}
+	return engine.ServiceDetected{}, false
+}
+
+func withProduct(svc engine.ServiceDetected, patterns []*regexp.Regexp, text string) engine.ServiceDetected {
+	for _, re := range patterns {
+		if m := re.FindStringSubmatch(text); m != nil {
+			svc.Product = m[1]
+			if len(m) > 2 {
+				svc.Version = m[2]
+			}
+			break
+		}
+	}
+	return svc
+}
+
+// parseMySQL reads the initial handshake packet: a 4 byte header, protocol
+// version 10, then the NUL terminated server version. Servers that refuse
+// our address send an error packet (0xff) instead.
+func parseMySQL(b []byte) (engine.ServiceDetected, bool) {
+	if len(b) > 7 && b[4] == 0xff && bytes.Contains(b, []byte("MySQL")) {
+		return engine.ServiceDetected{Service: "mysql", Product: "MySQL", Banner: firstLine(b[7:])}, true
+	}
+	if len(b) < 6 || b[4] != 0x0a {
+		return engine.ServiceDetected{}, false
+	}
+	end := bytes.IndexByte(b[5:], 0)
+	if end <= 0 {
+		return engine.ServiceDetected{}, false
+	}
+	version := string(b[5 : 5+end])
+	svc := engine.ServiceDetected{Service: "mysql", Product: "MySQL", Version: version}
+	if strings.Contains(version, "MariaDB") {
+		// e.g. "5.5.5-10.6.12-MariaDB-0ubuntu0.22.04.1"
+		svc.Product = "MariaDB"
+		parts := strings.Split(strings.TrimPrefix(version, "5.5.5-"), "-")
+		svc.Version = parts[0]
+	}
+	return svc, true
+}
+
+func parseRedis(b []byte) (engine.ServiceDetected, bool) {
+	s := string(b)
+	switch {
+	case strings.HasPrefix(s, "-NOAUTH"), strings.HasPrefix(s, "-DENIED"):
+		return engine.ServiceDetected{Service: "redis", Product: "Redis", Banner: firstLine(b)}, true
+	case strings.HasPrefix(s, "$") && strings.Contains(s, "redis_version:"):
+		svc := engine.ServiceDetected{Service: "redis", Product: "Redis"}
+		for _, line := range strings.Split(s, "\n") {
+			if v, ok := strings.CutPrefix(strings.TrimSpace(line), "redis_version:"); ok {
+				svc.Version = v
+			}
+		}
+		return svc, true
+	}
+	return engine.ServiceDetected{}, false
+}
+
+func parseHTTP(b []byte) (engine.ServiceDetected, bool) {
+	if !bytes.HasPrefix(b, []byte("HTTP/")) {
+		return engine.ServiceDetected{}, false
+	}
+	svc := engine.ServiceDetected{Service: "http", Banner: firstLine(b)}
+	// The reply may be cut short, so parse headers leniently
+	resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(b)), nil)
+	if err == nil {
+		resp.Body.Close()
+		if m := serverHeader.FindStringSubmatch(resp.Header.Get("Server")); m != nil {
+			svc.Product, svc.Version = m[1], m[2]
+		}
+	}
+	return svc, true
+}
+
+// plainToTLS matches the error pages web servers send when a plain
+// HTTP request reaches a TLS port
+var plainToTLS = regexp.MustCompile(`(?i)(plain HTTP request was sent to HTTPS port|HTTP request to an HTTPS server|speaking plain HTTP to an SSL-enabled)`)
+
+func wantsTLS(b []byte) bool {
+	return bytes.HasPrefix(b, []byte("HTTP/1.")) && bytes.Contains(b[:min(len(b), 16)], []byte(" 400")) && plainToTLS.Match(b)
+}
+
+func firstLine(b []byte) string {
+	line, _, _ := strings.Cut(string(b), "\n")
+	line = strings.TrimSpace(line)
+	if len(line) > 200 {
+		line = line[:200]
+	}
+	return strings.ToValidUTF8(line, "?")
+}
@@ -0,0 +1,94 @@
+package modules
+
+import (
+	"context"
+	"net/url"
+	"strings"
+
+	"gorecTool/internal/engine"
+)
+
+// Set is every module wired to one engine. Both front-ends build one of
+// these instead of constructing modules one by one.
+type Set struct {
+	Subdomains *SubdomainModule
+	Ports      *PortScanner
+	Http       *HttpAnalyzer
+	Files      *FileHunter
+	Takeover   *TakeoverChecker
+	Services   *ServiceDetector
+}
+
+func NewSet(brain *engine.DecisionEngine) *Set {
+	return &Set{
+		Subdomains: NewSubdomainModule(brain),
+		Ports:      NewPortScanner(brain),
+		Http:       NewHttpAnalyzer(brain),
+		Files:      NewFileHunter(brain),
+		Takeover:   NewTakeoverChecker(brain),
+		Services:   NewServiceDetector(brain),
+	}
+}
+
+// Actions maps the action names used in rule files to module calls.
+// Each action reads the fields it needs from the typed event payload.
+func (s *Set) Actions() map[string]func(context.Context, engine.Event) {
+	return map[string]func(context.Context, engine.Event){
+		// PORT_OPEN -> find out what listens there (TCP only, the UDP
+		// probe that opened the port already spoke its protocol), on the
+		// address it was found open on
+		"detect-service": func(ctx context.Context, e engine.Event) {
+			if port, ok := e.Port(); ok && e.Protocol() != "udp" {
+				s.Services.Detect(ctx, e.Target, e.IP(), port)
+			}
+		},
+		// SERVICE_DETECTED / PORT_OPEN -> fingerprint the web server on that
+		// port (a detected service tells us which scheme to try first)
+		"http-analyze": func(ctx context.Context, e engine.Event) {
+			scheme := ""
+			if svc, ok := e.Payload.(engine.ServiceDetected); ok {
+				scheme = "http"
+				if svc.TLS {
+					scheme = "https"
+				}
+			}
+			if port, ok := e.Port(); ok {
+				s.Http.Analyze(ctx, e.Target, e.IP(), port, scheme)
+			}
+		},
+		// HTTP_SERVICE -> look for sensitive files matching the tech stack,
+		// under the application root the redirects led to
+		"hunt-files": func(ctx context.Context, e engine.Event) {
+			if svc, ok := e.Payload.(engine.HttpService); ok {
+				// Only hunt where the analyzer started if the redirects
+				// left this host
+				base := svc.Root()
+				if u, err := url.Parse(base); err != nil || !strings.EqualFold(u.Hostname(), e.Target) {
+					base = strings.TrimSuffix(svc.URL, "/")
+				}
+				s.Files.Hunt(ctx, e.Target, svc.IP, svc.Port, base, svc.Tech)
+			}
+		},
+		// SUBDOMAIN_FOUND -> quick port scan of the new host (if it resolves)
+		"port-scan": func(ctx context.Context, e engine.Event) {
+			if sub, ok := e.Payload.(engine.SubdomainFound); ok && !sub.Alive {
+				return
+			}
+			s.Ports.ScanTarget(ctx, e.Target, nil)
+		},
+		// SUBDOMAIN_FOUND -> follow the CNAME chain, look for a takeover
+		"takeover-check": func(ctx context.Context, e engine.Event) {
+			s.Takeover.Check(ctx, e.Target)
+		},
+	}
+}
+
+// allowed is the scope guard at each module's entry point, for callers that
+// don't go through the engine's rules (the CLI's own scans, the GUI)
+func allowed(brain *engine.DecisionEngine, module, target string) bool {
+	if brain.InScope(target) {
+		return true
+	}
+	brain.Logf("[Scope] %s is out of scope, %s skipped.", target, module)
+	return false
+}
@@ -0,0 +1,285 @@
+package modules
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// SubdomainSource is a passive source of subdomain names. Fetch may return
+// names outside the root domain or with wildcards; SubdomainModule cleans
+// them up. Every source talks to BaseURL through Client so it can be pointed
+// at an httptest server.
+type SubdomainSource interface {
+	Name() string
+	Fetch(ctx context.Context, domain string) ([]string, error)
+}
+
+// DefaultSources returns every built-in passive source
+func DefaultSources() []SubdomainSource {
+	return []SubdomainSource{
+		&CrtShSource{},
+		&HackerTargetSource{},
+		&CertSpotterSource{},
+		&WaybackSource{},
+		&AlienVaultSource{},
+		&AnubisSource{},
+	}
+}
+
+const userAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36"
+
+// errRetry marks a failure worth trying again (overload, rate limit)
+type errRetry struct{ error }
+
+// get issues a GET and returns the response if it was a 200
+func get(ctx context.Context, client *http.Client, rawURL string, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, errRetry{err}
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		err := fmt.Errorf("unexpected status %d", resp.StatusCode)
+		switch resp.StatusCode {
+		case 429, 502, 503, 504:
+			return nil, errRetry{err}
+		}
+		return nil, err
+	}
+	return resp, nil
+}
+
+// getJSON GETs rawURL and decodes the JSON body into v
+func getJSON(ctx context.Context, client *http.Client, rawURL string, header http.Header, v interface{}) error {
+	resp, err := get(ctx, client, rawURL, header)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		// Sometimes they send 200 OK but with broken HTML/JSON
+		return errRetry{fmt.Errorf("decoding response: %w", err)}
+	}
+	return nil
+}
+
+func clientOr(c *http.Client, timeout time.Duration) *http.Client {
+	if c != nil {
+		return c
+	}
+	return &http.Client{Timeout: timeout}
+}
+
+func baseOr(base, def string) string {
+	if base != "" {
+		return strings.TrimRight(base, "/")
+	}
+	return def
+}
+
+// CrtShSource queries crt.sh (Certificate Transparency). It is often
+// overloaded, so failures are retried a few times.
+type CrtShSource struct {
+	BaseURL    string // Default https://crt.sh
+	Client     *http.Client
+	Retries    int           // Default 3
+	RetryDelay time.Duration // Default 3s
+}
+
+// CrtShResult represents the JSON structure returned by crt.sh
+type CrtShResult struct {
+	NameValue string `json:"name_value"`
+}
+
+func (c *CrtShSource) Name() string { return "crt.sh" }
+
+func (c *CrtShSource) Fetch(ctx context.Context, domain string) ([]string, error) {
+	u := fmt.Sprintf("%s/?q=%s&output=json", baseOr(c.BaseURL, "https://crt.sh"), url.QueryEscape("%."+domain))
+	client := clientOr(c.Client, 20*time.Second)
+	retries, delay := c.Retries, c.RetryDelay
+	if retries <= 0 {
+		retries = 3
+	}
+	if delay <= 0 {
+		delay = 3 * time.Second
+	}
+
+	var results []CrtShResult
+	var err error
+	for i := 0; i < retries; i++ {
+		if err = getJSON(ctx, client, u, nil, &results); err == nil {
+			break
+		}
+		var retry errRetry
+		if !errors.As(err, &retry) || i == retries-1 || !sleepCtx(ctx, delay) {
+			return nil, err
+		}
+	}
+
+	// A single certificate can list several names separated by newlines
+	var names []string
+	for _, r := range results {
+		names = append(names, strings.Split(r.NameValue, "\n")...)
+	}
+	return names, nil
+}
+
+// HackerTargetSource queries the HackerTarget host search API
+type HackerTargetSource struct {
+	BaseURL string // Default https://api.hackertarget.com
+	Client  *http.Client
+}
+
+func (h *HackerTargetSource) Name() string { return "hackertarget" }
+
+func (h *HackerTargetSource) Fetch(ctx context.Context, domain string) ([]string, error) {
+	u := fmt.Sprintf("%s/hostsearch/?q=%s", baseOr(h.BaseURL, "https://api.hackertarget.com"), url.QueryEscape(domain))
+	resp, err := get(ctx, clientOr(h.Client, 10*time.Second), u, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// HackerTarget returns CSV lines: "hostname,ip"
+	// Example:
+	// www.google.com,142.250.1.1
+	// mail.google.com,142.250.1.2
+	// Errors (e.g. "API count exceeded") come back as a single plain line.
+	var names []string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		host, _, found := strings.Cut(line, ",")
+		if !found {
+			if line != "" && len(names) == 0 {
+				return nil, fmt.Errorf("%s", line)
+			}
+			continue
+		}
+		names = append(names, strings.TrimSpace(host))
+	}
+	return names, scanner.Err()
+}
+
+// CertSpotterSource queries the Cert Spotter CT log search API. Token is
+// optional; without it the free, rate limited tier is used.
+type CertSpotterSource struct {
+	BaseURL string // Default https://api.certspotter.com
+	Client  *http.Client
+	Token   string
+}
+
+func (c *CertSpotterSource) Name() string { return "certspotter" }
+
+func (c *CertSpotterSource) Fetch(ctx context.Context, domain string) ([]string, error) {
+	u := fmt.Sprintf("%s/v1/issuances?domain=%s&include_subdomains=true&expand=dns_names",
+		baseOr(c.BaseURL, "https://api.certspotter.com"), url.QueryEscape(domain))
+	var header http.Header
+	if c.Token != "" {
+		header = http.Header{"Authorization": {"Bearer " + c.Token}}
+	}
+
+	var issuances []struct {
+		DNSNames []string `json:"dns_names"`
+	}
+	if err := getJSON(ctx, clientOr(c.Client, 20*time.Second), u, header, &issuances); err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, is := range issuances {
+		names = append(names, is.DNSNames...)
+	}
+	return names, nil
+}
+
+// WaybackSource pulls hostnames out of the URLs the Wayback Machine archived
+type WaybackSource struct {
+	BaseURL string // Default https://web.archive.org
+	Client  *http.Client
+}
+
+func (w *WaybackSource) Name() string { return "wayback" }
+
+func (w *WaybackSource) Fetch(ctx context.Context, domain string) ([]string, error) {
+	u := fmt.Sprintf("%s/cdx/search/cdx?url=%s&output=json&fl=original&collapse=urlkey",
+		baseOr(w.BaseURL, "https://web.archive.org"), url.QueryEscape("*."+domain+"/*"))
+
+	// Rows of fields; the first row is the header (["original"])
+	var rows [][]string
+	if err := getJSON(ctx, clientOr(w.Client, 30*time.Second), u, nil, &rows); err != nil {
+		return nil, err
+	}
+	var names []string
+	for i, row := range rows {
+		if i == 0 || len(row) == 0 {
+			continue
+		}
+		raw := row[0]
+		if !strings.Contains(raw, "://") {
+			raw = "http://" + raw
+		}
+		if parsed, err := url.Parse(raw); err == nil && parsed.Hostname() != "" {
+			names = append(names, parsed.Hostname())
+		}
+	}
+	return names, nil
+}
+
+// AlienVaultSource queries the passive DNS data of AlienVault OTX
+type AlienVaultSource struct {
+	BaseURL string // Default https://otx.alienvault.com
+	Client  *http.Client
+}
+
+func (a *AlienVaultSource) Name() string { return "alienvault" }
+
+func (a *AlienVaultSource) Fetch(ctx context.Context, domain string) ([]string, error) {
+	u := fmt.Sprintf("%s/api/v1/indicators/domain/%s/passive_dns",
+		baseOr(a.BaseURL, "https://otx.alienvault.com"), url.PathEscape(domain))
+
+	var result struct {
+		PassiveDNS []struct {
+			Hostname string `json:"hostname"`
+		} `json:"passive_dns"`
+	}
+	if err := getJSON(ctx, clientOr(a.Client, 20*time.Second), u, nil, &result); err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, r := range result.PassiveDNS {
+		names = append(names, r.Hostname)
+	}
+	return names, nil
+}
+
+// AnubisSource queries the Anubis subdomain database (jldc.me)
+type AnubisSource struct {
+	BaseURL string // Default https://jldc.me
+	Client  *http.Client
+}
+
+func (a *AnubisSource) Name() string { return "anubis" }
+
+func (a *AnubisSource) Fetch(ctx context.Context, domain string) ([]string, error) {
+	u := fmt.Sprintf("%s/anubis/subdomains/%s", baseOr(a.BaseURL, "https://jldc.me"), url.PathEscape(domain))
+	var names []string
+	if err := getJSON(ctx, clientOr(a.Client, 20*time.Second), u, nil, &names); err != nil {
+		return nil, err
+	}
+	return names, nil
+}
@@ -0,0 +1,188 @@
+package modules
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+
+	"gorecTool/internal/engine"
+	"gorecTool/internal/resolver"
+)
+
+// sourceFixture describes how one passive source is queried and what its
+// API answers look like
+type sourceFixture struct {
+	source    func(baseURL string) SubdomainSource
+	path      string // Request path the source must hit
+	normal    string // Body listing a.example.com and b.example.com
+	empty     string // Body with no results
+	malformed string // Body the source must reject
+}
+
+var sourceFixtures = map[string]sourceFixture{
+	"crt.sh": {
+		source:    func(u string) SubdomainSource { return &CrtShSource{BaseURL: u, Retries: 1} },
+		path:      "/",
+		normal:    `[{"name_value":"a.example.com\nb.example.com"}]`,
+		empty:     `[]`,
+		malformed: `<html>overloaded</html>`,
+	},
+	"hackertarget": {
+		source:    func(u string) SubdomainSource { return &HackerTargetSource{BaseURL: u} },
+		path:      "/hostsearch/",
+		normal:    "a.example.com,192.0.2.1\nb.example.com,192.0.2.2\n",
+		empty:     "",
+		malformed: "API count exceeded - Increase Quota with Membership\n",
+	},
+	"certspotter": {
+		source:    func(u string) SubdomainSource { return &CertSpotterSource{BaseURL: u} },
+		path:      "/v1/issuances",
+		normal:    `[{"dns_names":["a.example.com"]},{"dns_names":["b.example.com"]}]`,
+		empty:     `[]`,
+		malformed: `{"dns_names":`,
+	},
+	"wayback": {
+		source:    func(u string) SubdomainSource { return &WaybackSource{BaseURL: u} },
+		path:      "/cdx/search/cdx",
+		normal:    `[["original"],["https://a.example.com/login"],["b.example.com:80/robots.txt"]]`,
+		empty:     `[]`,
+		malformed: `not json`,
+	},
+	"alienvault": {
+		source:    func(u string) SubdomainSource { return &AlienVaultSource{BaseURL: u} },
+		path:      "/api/v1/indicators/domain/example.com/passive_dns",
+		normal:    `{"passive_dns":[{"hostname":"a.example.com"},{"hostname":"b.example.com"}]}`,
+		empty:     `{"passive_dns":[]}`,
+		malformed: `[`,
+	},
+	"anubis": {
+		source:    func(u string) SubdomainSource { return &AnubisSource{BaseURL: u} },
+		path:      "/anubis/subdomains/example.com",
+		normal:    `["a.example.com","b.example.com"]`,
+		empty:     `[]`,
+		malformed: `{`,
+	},
+}
+
+// serve answers every request with status and body, failing the test if a
+// request goes to another path than path
+func serve(t *testing.T, path string, status int, body string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != path {
+			t.Errorf("request to %s, want %s", r.URL.Path, path)
+		}
+		w.WriteHeader(status)
+		fmt.Fprint(w, body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestSourcesFetch(t *testing.T) {
+	for name, f := range sourceFixtures {
+		t.Run(name, func(t *testing.T) {
+			src := f.source(serve(t, f.path, http.StatusOK, f.normal).URL)
+			if src.Name() != name {
+				t.Errorf("Name() = %q, want %q", src.Name(), name)
+			}
+			got, err := src.Fetch(context.Background(), "example.com")
+			if err != nil {
+				t.Fatalf("normal response: %v", err)
+			}
+			if want := []string{"a.example.com", "b.example.com"}; !slices.Equal(got, want) {
+				t.Errorf("normal response: got %q, want %q", got, want)
+			}
+
+			got, err = f.source(serve(t, f.path, http.StatusOK, f.empty).URL).Fetch(context.Background(), "example.com")
+			if err != nil || len(got) != 0 {
+				t.Errorf("empty response: got %q, %v, want no names and no error", got, err)
+			}
+
+			if _, err := f.source(serve(t, f.path, http.StatusInternalServerError, f.normal).URL).Fetch(context.Background(), "example.com"); err == nil {
+				t.Error("HTTP 500: no error")
+			}
+
+			if _, err := f.source(serve(t, f.path, http.StatusOK, f.malformed).URL).Fetch(context.Background(), "example.com"); err == nil {
+				t.Error("malformed body: no error")
+			}
+		})
+	}
+}
+
+// staticResolver resolves the names it knows to 192.0.2.1
+type staticResolver map[string]bool
+
+func (r staticResolver) LookupHost(_ context.Context, host string) ([]string, error) {
+	if r[host] {
+		return []string{"192.0.2.1"}, nil
+	}
+	return nil, resolver.ErrNotFound
+}
+
+func (staticResolver) Lookup(context.Context, string, string) ([]string, error) {
+	return nil, resolver.ErrNotFound
+}
+
+// recordingObserver keeps every event the engine saw
+type recordingObserver struct {
+	mu     sync.Mutex
+	events []engine.Event
+}
+
+func (o *recordingObserver) OnEvent(e engine.Event) {
+	o.mu.Lock()
+	o.events = append(o.events, e)
+	o.mu.Unlock()
+}
+
+func (o *recordingObserver) OnLog(string) {}
+
+func TestSubdomainSourceAttribution(t *testing.T) {
+	crt := sourceFixtures["crt.sh"]
+	anubis := sourceFixtures["anubis"]
+	hackertarget := sourceFixtures["hackertarget"]
+
+	brain := engine.NewEngine(context.Background())
+	seen := &recordingObserver{}
+	brain.AddObserver(seen)
+	go brain.Start()
+
+	s := NewSubdomainModule(brain)
+	s.Resolver = staticResolver{"a.example.com": true, "b.example.com": true, "c.example.com": true}
+	s.Sources = []SubdomainSource{
+		crt.source(serve(t, crt.path, http.StatusOK, `[{"name_value":"a.example.com\nB.example.com."}]`).URL),
+		anubis.source(serve(t, anubis.path, http.StatusOK, `["b.example.com","c.example.com","evil.com"]`).URL),
+		hackertarget.source(serve(t, hackertarget.path, http.StatusBadGateway, "").URL),
+	}
+	alive := s.Run(context.Background(), "example.com")
+	brain.Wait()
+
+	slices.Sort(alive)
+	if want := []string{"a.example.com", "b.example.com", "c.example.com"}; !slices.Equal(alive, want) {
+		t.Errorf("alive = %q, want %q", alive, want)
+	}
+	want := map[string][]string{
+		"a.example.com": {"crt.sh"},
+		"b.example.com": {"crt.sh", "anubis"},
+		"c.example.com": {"anubis"},
+	}
+	got := make(map[string][]string)
+	for _, e := range seen.events {
+		if sub, ok := e.Payload.(engine.SubdomainFound); ok {
+			got[e.Target] = sub.Sources
+		}
+	}
+	if len(got) != len(want) {
+		t.Errorf("published %d subdomains, want %d: %v", len(got), len(want), got)
+	}
+	for name, sources := range want {
+		if !slices.Equal(got[name], sources) {
+			t.Errorf("%s: sources %q, want %q", name, got[name], sources)
+		}
+	}
+}
@@ -1,11 +1,11 @@
 package modules
 
 import (
-	"encoding/json"
-	"fmt"
+	"context"
+	"errors"
 	"gorecTool/internal/engine"
+	"gorecTool/internal/resolver"
 	"net"
-	"net/http"
 	"strings"
 	"sync"
 	"time"
@@ -13,103 +13,109 @@ import (
 
 type SubdomainModule struct {
 	Brain *engine.DecisionEngine
-}
 
-func NewSubdomainModule(brain *engine.DecisionEngine) *SubdomainModule {
-	return &SubdomainModule{Brain: brain}
-}
-
-// CrtShResult represents the JSON structure returned by crt.sh
-type CrtShResult struct {
-	NameValue string `json:"name_value"`
-}
+	// Sources are queried concurrently by Run (DefaultSources if unchanged)
+	Sources []SubdomainSource
 
-// Run is the main entry point for this module
-func (s *SubdomainModule) Run(rootDomain string) []string {
-	fmt.Printf("[Subdomain] 🔍 querying CRT.sh for %s (Passive)... \n", rootDomain)
+	// Wordlist, if set, is always brute forced. Without it the small
+	// built-in list is only tried when every passive source came back empty.
+	Wordlist []string
 
-	// 1. Fetch raw domains from API
-	rawDomains := s.fetchFromCrtSh(rootDomain)
-	fmt.Printf("[Subdomain] Found %d raw entries. Cleaning...\n", len(rawDomains))
+	// Permutations generates alterations of the names found so far
+	// (dev-api, api2, ...) and tries those too.
+	Permutations bool
 
-	// 2. Clean and Deduplicate
-	cleanDomains := s.cleanDomains(rawDomains, rootDomain)
-	fmt.Printf("[Subdomain] %d unique subdomains found. Validating DNS...\n", len(cleanDomains))
-
-	// 3. Validate (DNS Resolution) and Publish
-	return s.validateAndPublish(cleanDomains)
+	// Resolver validates names and brute force guesses (the system
+	// resolver unless a pool of DNS servers is configured)
+	Resolver resolver.Resolver
 }
 
-func (s *SubdomainModule) fetchFromCrtSh(domain string) []string {
-	// 1. Define the URL
-	url := fmt.Sprintf("https://crt.sh/?q=%%25.%s&output=json", domain)
-
-	var results []CrtShResult
-
-	// 2. Retry Logic (Try 3 times)
-	maxRetries := 3
-	for i := 0; i < maxRetries; i++ {
-
-		client := &http.Client{Timeout: 20 * time.Second}
-		req, _ := http.NewRequest("GET", url, nil)
+func NewSubdomainModule(brain *engine.DecisionEngine) *SubdomainModule {
+	return &SubdomainModule{Brain: brain, Sources: DefaultSources(), Resolver: resolver.System{}}
+}
 
-		// Add a User-Agent (Sometimes helps avoid blocks)
-		req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36")
+// Run is the main entry point for this module. On cancellation it returns
+// whatever was confirmed alive so far.
+func (s *SubdomainModule) Run(ctx context.Context, rootDomain string) []string {
+	s.Brain.Logf("[Subdomain] 🔍 Starting Passive Recon on %s (%d sources)...", rootDomain, len(s.Sources))
 
-		resp, err := client.Do(req)
+	// 1. Fetch raw domains from every passive source at once
+	found := make([][]string, len(s.Sources))
+	var wg sync.WaitGroup
+	for i, src := range s.Sources {
+		wg.Add(1)
+		go func(i int, src SubdomainSource) {
+			defer wg.Done()
+			names, err := src.Fetch(ctx, rootDomain)
+			if err != nil {
+				if ctx.Err() == nil {
+					s.Brain.Logf("[!] %s failed: %v", src.Name(), err)
+				}
+				return
+			}
+			s.Brain.Logf("[Subdomain] %s returned %d entries", src.Name(), len(names))
+			found[i] = names
+		}(i, src)
+	}
+	wg.Wait()
 
-		// Network error? Wait and retry.
-		if err != nil {
-			fmt.Printf("[Error] Network failure: %v. Retrying (%d/%d)...\n", err, i+1, maxRetries)
-			time.Sleep(3 * time.Second)
-			continue
+	// Merge, remembering every source that reported a name (in source order)
+	sources := make(map[string][]string)
+	add := func(names []string, source string) {
+		for _, d := range names {
+			d = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(d)), ".")
+			if d == "" || containsString(sources[d], source) {
+				continue
+			}
+			sources[d] = append(sources[d], source)
 		}
+	}
+	for i, src := range s.Sources {
+		add(found[i], src.Name())
+	}
 
-		// 3. Handle Status Codes
-		if resp.StatusCode == 429 || resp.StatusCode == 502 || resp.StatusCode == 503 {
-			// Rate limited or Server overload
-			resp.Body.Close() // Close before sleeping
-			fmt.Printf("[!] CRT.sh is overloaded (Status %d). Sleeping 5s before retry (%d/%d)...\n", resp.StatusCode, i+1, maxRetries)
-			time.Sleep(5 * time.Second) // Wait longer for 429s
-			continue
-		}
+	// 2. Active brute force: always with a --wordlist, otherwise only if
+	// passive recon failed or found nothing
+	words := s.Wordlist
+	if len(words) == 0 && len(sources) == 0 {
+		s.Brain.Log("[Subdomain] Passive sources failed or found nothing. Switching to ACTIVE Brute Force...")
+		words = commonSubs
+	}
+	if (len(words) > 0 || s.Permutations) && ctx.Err() == nil {
+		// A wildcard record would make every guess look alive
+		wildcard := s.detectWildcard(ctx, rootDomain)
 
-		if resp.StatusCode != 200 {
-			// Some other permanent error (404, 403)
-			fmt.Printf("[Error] CRT.sh returned unexpected status: %d\n", resp.StatusCode)
-			resp.Body.Close()
-			break
+		if len(words) > 0 {
+			add(s.bruteForce(ctx, withRoot(words, rootDomain), wildcard), "bruteforce")
 		}
-
-		// 4. Decode JSON (Only if 200 OK)
-		if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
-			// Sometimes they send 200 OK but with broken HTML/JSON
-			resp.Body.Close()
-			// Only retry if it looks like a temporary glitch
-			if i < maxRetries-1 {
-				fmt.Println("[!] Failed to decode JSON. Retrying...")
-				time.Sleep(2 * time.Second)
-				continue
+		if s.Permutations && ctx.Err() == nil {
+			known := make([]string, 0, len(sources))
+			for d := range sources {
+				known = append(known, d)
 			}
-		} else {
-			// Success!
-			resp.Body.Close()
-			break
+			guesses := permutations(s.cleanDomains(known, rootDomain), rootDomain)
+			s.Brain.Logf("[Subdomain] Trying %d permutations of known names...", len(guesses))
+			add(s.bruteForce(ctx, guesses, wildcard), "permutation")
 		}
 	}
+	s.Brain.Logf("[Subdomain] Found %d raw entries. Cleaning...", len(sources))
 
-	// 5. Convert results to string slice
-	var output []string
-	for _, r := range results {
-		output = append(output, r.NameValue)
+	// 3. Clean and Deduplicate
+	raw := make([]string, 0, len(sources))
+	for d := range sources {
+		raw = append(raw, d)
 	}
-	return output
+	cleanDomains := s.cleanDomains(raw, rootDomain)
+	s.Brain.Logf("[Subdomain] %d unique subdomains found. Validating DNS...", len(cleanDomains))
+
+	// 4. Validate (DNS Resolution) and Publish
+	return s.validateAndPublish(ctx, cleanDomains, sources)
 }
 
 func (s *SubdomainModule) cleanDomains(raw []string, rootDomain string) []string {
 	uniqueMap := make(map[string]bool)
 	var clean []string
-	// fmt.Println(raw, rootDomain)
+	root := strings.ToLower(rootDomain)
 	for _, domain := range raw {
 		// Convert to lowercase
 		d := strings.ToLower(domain)
@@ -119,8 +125,10 @@ func (s *SubdomainModule) cleanDomains(raw []string, rootDomain string) []string
 			continue
 		}
 
-		// Ensure it actually belongs to our target (cleanup garbage data)
-		if !strings.HasSuffix(d, rootDomain) {
+		// Ensure it actually belongs to our target (cleanup garbage data).
+		// Match on a label boundary: "badexample.com" is not ours.
+		d = strings.TrimSuffix(d, ".")
+		if d != root && !strings.HasSuffix(d, "."+root) {
 			continue
 		}
 
@@ -133,8 +141,7 @@ func (s *SubdomainModule) cleanDomains(raw []string, rootDomain string) []string
 	return clean
 }
 
-func (s *SubdomainModule) validateAndPublish(domains []string) []string {
-	fmt.Println(domains)
+func (s *SubdomainModule) validateAndPublish(ctx context.Context, domains []string, sources map[string][]string) []string {
 	var alive []string
 	var wg sync.WaitGroup
 	var mu sync.Mutex
@@ -147,24 +154,125 @@ func (s *SubdomainModule) validateAndPublish(domains []string) []string {
 			sem <- struct{}{}
 			defer func() { <-sem }()
 
-			if ip, err := net.LookupHost(subdomain); err == nil {
-
-				mu.Lock()
-				alive = append(alive, subdomain) // Add to list
-				mu.Unlock()
-
-				fmt.Printf("   [+] Alive: %s at ip : %s\n", subdomain, ip)
-
-				// We still publish for the log, but we won't use this event to trigger scans anymore
+			ip, err := s.Resolver.LookupHost(ctx, subdomain)
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				// Dead names are published too: a dangling CNAME is a lead
 				s.Brain.Publish(engine.Event{
 					Type:    engine.EventSubdomainFound,
 					Target:  subdomain,
-					Payload: "Passive-Source",
+					Payload: engine.SubdomainFound{Sources: sources[subdomain], Alive: false, Addresses: []string{}},
 				})
+				return
 			}
+
+			mu.Lock()
+			alive = append(alive, subdomain) // Add to list
+			mu.Unlock()
+
+			s.Brain.Logf("   [+] Alive: %s at ip : %s", subdomain, ip)
+
+			s.Brain.Publish(engine.Event{
+				Type:    engine.EventSubdomainFound,
+				Target:  subdomain,
+				Payload: engine.SubdomainFound{Sources: sources[subdomain], Alive: true, Addresses: ip},
+			})
+			s.publishRecords(ctx, subdomain, ip)
 		}(d)
 	}
 	wg.Wait()
 
 	return alive
 }
+
+// publishRecords publishes a DNS_RECORD event for every A, AAAA, CNAME, MX,
+// TXT and NS record of a live host. The addresses are already known from
+// validation, so only the other types cost a query.
+func (s *SubdomainModule) publishRecords(ctx context.Context, host string, addrs []string) {
+	var records []engine.DNSRecord
+	for _, a := range addrs {
+		rtype := resolver.TypeA
+		if ip := net.ParseIP(a); ip != nil && ip.To4() == nil {
+			rtype = resolver.TypeAAAA
+		}
+		records = append(records, engine.DNSRecord{Type: rtype, Value: a})
+	}
+	for _, rtype := range []string{resolver.TypeCNAME, resolver.TypeMX, resolver.TypeTXT, resolver.TypeNS} {
+		values, err := s.Resolver.Lookup(ctx, host, rtype)
+		if err != nil {
+			if !errors.Is(err, resolver.ErrNotFound) && ctx.Err() == nil {
+				s.Brain.Logf("[DNS] %s %s lookup failed: %v", host, rtype, err)
+			}
+			continue
+		}
+		for _, v := range values {
+			records = append(records, engine.DNSRecord{Type: rtype, Value: v})
+		}
+	}
+
+	for _, r := range records {
+		s.Brain.Publish(engine.Event{Type: engine.EventDNSRecord, Target: host, Payload: r})
+	}
+}
+
+// LabelAddresses looks up the PTR name of each raw IP target (no subdomain
+// discovery happens for those), publishes it as a DNS_RECORD and returns
+// the first name per address. Addresses without one are left out.
+func (s *SubdomainModule) LabelAddresses(ctx context.Context, ips []string) map[string]string {
+	labels := make(map[string]string)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	sem := make(chan struct{}, 50)
+
+	for _, ip := range ips {
+		wg.Add(1)
+		go func(ip string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			names, err := s.Resolver.Lookup(ctx, ip, resolver.TypePTR)
+			if err != nil {
+				if !errors.Is(err, resolver.ErrNotFound) && ctx.Err() == nil {
+					s.Brain.Logf("[DNS] %s PTR lookup failed: %v", ip, err)
+				}
+				return
+			}
+			mu.Lock()
+			labels[ip] = names[0]
+			mu.Unlock()
+			for _, name := range names {
+				s.Brain.Publish(engine.Event{
+					Type:    engine.EventDNSRecord,
+					Target:  ip,
+					Payload: engine.DNSRecord{Type: resolver.TypePTR, Value: name},
+				})
+			}
+		}(ip)
+	}
+	wg.Wait()
+	return labels
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// sleepCtx waits for d, returning early (false) if ctx is cancelled
+func sleepCtx(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,236 @@
+package modules
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"gorecTool/internal/engine"
+	"gorecTool/internal/resolver"
+)
+
+//go:embed takeover_signatures.json
+var defaultSignatures []byte
+
+// TakeoverSignature describes a hosting service that lets anyone claim a
+// name once its owner deleted the resource a CNAME still points at.
+type TakeoverSignature struct {
+	Service     string   `json:"service"`
+	CNAME       []string `json:"cname"`       // Suffixes of CNAME targets served by the service
+	Fingerprint []string `json:"fingerprint"` // Body snippets of the "unclaimed" page
+	NXDomain    bool     `json:"nxdomain"`    // Vulnerable when the CNAME target no longer resolves
+}
+
+// DefaultSignatures returns the bundled signature database
+func DefaultSignatures() []TakeoverSignature {
+	sigs, err := ParseSignatures(defaultSignatures)
+	if err != nil {
+		panic(fmt.Sprintf("modules: bad embedded takeover_signatures.json: %v", err))
+	}
+	return sigs
+}
+
+// LoadSignatures reads a signature database in the same JSON format as the
+// bundled one, so it can be updated without a rebuild
+func LoadSignatures(filename string) ([]TakeoverSignature, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	sigs, err := ParseSignatures(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return sigs, nil
+}
+
+func ParseSignatures(data []byte) ([]TakeoverSignature, error) {
+	var sigs []TakeoverSignature
+	if err := json.Unmarshal(data, &sigs); err != nil {
+		return nil, err
+	}
+	for i, s := range sigs {
+		if s.Service == "" || len(s.CNAME) == 0 {
+			return nil, fmt.Errorf("signature %d: service and cname are required", i+1)
+		}
+		if len(s.Fingerprint) == 0 && !s.NXDomain {
+			return nil, fmt.Errorf("signature %q: needs a fingerprint or nxdomain", s.Service)
+		}
+	}
+	return sigs, nil
+}
+
+// matches reports whether cname is one of the signature's domains or under
+// one of them. A label boundary is required: "evilgithub.io" is not
+// "github.io".
+func (s TakeoverSignature) matches(cname string) bool {
+	cname = strings.TrimSuffix(strings.ToLower(cname), ".")
+	for _, suffix := range s.CNAME {
+		suffix = strings.Trim(strings.ToLower(suffix), ".")
+		if cname == suffix || strings.HasSuffix(cname, "."+suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// TakeoverChecker looks for subdomains whose CNAME points at a resource
+// nobody owns anymore
+type TakeoverChecker struct {
+	Brain      *engine.DecisionEngine
+	Resolver   resolver.Resolver
+	Signatures []TakeoverSignature
+	Client     *http.Client
+}
+
+func NewTakeoverChecker(brain *engine.DecisionEngine) *TakeoverChecker {
+	return &TakeoverChecker{
+		Brain:      brain,
+		Resolver:   resolver.System{},
+		Signatures: DefaultSignatures(),
+		Client: &http.Client{
+			Transport: newTransport(brain),
+			Timeout:   10 * time.Second,
+			// The takeover page is served by the dangling host itself;
+			// don't wander off to hosts we may not touch
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				if len(via) >= maxRedirects || !mayFollow(brain, via[0].URL.Hostname(), req.URL.Hostname()) {
+					return http.ErrUseLastResponse
+				}
+				return nil
+			},
+		},
+	}
+}
+
+// maxChain bounds how many CNAME hops are followed
+const maxChain = 10
+
+// Check follows the CNAME chain of host and publishes a VULN_FOUND if it
+// ends at an unclaimed resource. It works for hosts that don't resolve at
+// all, which is where dangling records usually are.
+func (t *TakeoverChecker) Check(ctx context.Context, host string) {
+	if !allowed(t.Brain, "takeover check", host) {
+		return
+	}
+	chain := t.cnameChain(ctx, host)
+	if len(chain) == 0 {
+		return
+	}
+	final := chain[len(chain)-1]
+	_, err := t.Resolver.LookupHost(ctx, final)
+	dangling := errors.Is(err, resolver.ErrNotFound) || isNotFound(err)
+	path := host + " -> " + strings.Join(chain, " -> ")
+
+	for _, sig := range t.Signatures {
+		if !chainMatches(sig, chain) {
+			continue
+		}
+		if sig.NXDomain && dangling {
+			t.report(host, sig.Service, "", fmt.Sprintf("CNAME %s does not resolve", path))
+			return
+		}
+		if dangling || len(sig.Fingerprint) == 0 {
+			continue
+		}
+		if url, snippet := t.fingerprint(ctx, host, sig.Fingerprint); url != "" {
+			t.report(host, sig.Service, url, fmt.Sprintf("CNAME %s, page says %q", path, snippet))
+			return
+		}
+	}
+
+	// No known service, but a CNAME into nothing is worth a look
+	if dangling {
+		t.Brain.Publish(engine.Event{
+			Type:   engine.EventVulnFound,
+			Target: host,
+			Payload: engine.VulnFound{
+				Name:     "Dangling CNAME",
+				Severity: engine.SeverityMedium,
+				Evidence: fmt.Sprintf("CNAME %s does not resolve", path),
+			},
+		})
+	}
+}
+
+func (t *TakeoverChecker) report(host, service, url, evidence string) {
+	t.Brain.Logf("[!!!] Possible subdomain takeover: %s (%s)", host, service)
+	t.Brain.Publish(engine.Event{
+		Type:   engine.EventVulnFound,
+		Target: host,
+		Payload: engine.VulnFound{
+			Name:     "Subdomain Takeover: " + service,
+			Severity: engine.SeverityHigh,
+			URL:      url,
+			Evidence: evidence,
+		},
+	})
+}
+
+// cnameChain returns the CNAME targets of host in order (empty if host is
+// not an alias)
+func (t *TakeoverChecker) cnameChain(ctx context.Context, host string) []string {
+	var chain []string
+	seen := map[string]bool{host: true}
+	name := host
+	for len(chain) < maxChain && ctx.Err() == nil {
+		targets, err := t.Resolver.Lookup(ctx, name, resolver.TypeCNAME)
+		if err != nil || len(targets) == 0 {
+			break
+		}
+		next := strings.ToLower(targets[0])
+		if seen[next] {
+			break // Loop
+		}
+		seen[next] = true
+		chain = append(chain, next)
+		name = next
+	}
+	return chain
+}
+
+func chainMatches(sig TakeoverSignature, chain []string) bool {
+	for _, c := range chain {
+		if sig.matches(c) {
+			return true
+		}
+	}
+	return false
+}
+
+// fingerprint fetches the host over http and https and returns the URL and
+// the first signature snippet found in a response body
+func (t *TakeoverChecker) fingerprint(ctx context.Context, host string, snippets []string) (string, string) {
+	for _, scheme := range []string{"http", "https"} {
+		url := scheme + "://" + host + "/"
+		req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+		if err != nil {
+			continue
+		}
+		resp, err := t.Client.Do(req)
+		if err != nil {
+			continue
+		}
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+		resp.Body.Close()
+		for _, s := range snippets {
+			if strings.Contains(string(body), s) {
+				return url, s
+			}
+		}
+	}
+	return "", ""
+}
+
+func isNotFound(err error) bool {
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr) && dnsErr.IsNotFound
+}
@@ -0,0 +1,182 @@
+package modules
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"strconv"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+
+	"gorecTool/internal/engine"
+)
+
+// DefaultUDPPorts are the UDP services we have a probe for. Most UDP
+// services stay silent unless the datagram makes sense to them, so scanning
+// a port without a probe rarely tells open from filtered.
+var DefaultUDPPorts = []int{53, 69, 123, 137, 161, 500, 1434, 1900, 4500, 5353, 11211}
+
+// udpProbes are protocol-specific payloads that make a listening service
+// answer. Ports without one get an empty datagram.
+var udpProbes = map[int][]byte{
+	53:    dnsQuery(".", dnsmessage.TypeNS),
+	69:    []byte("\x00\x01gorecon.txt\x00octet\x00"), // TFTP read request, answered by an error
+	123:   ntpRequest(),
+	137:   nbstatQuery(),
+	161:   snmpGetRequest(),
+	500:   ikeMainMode(),
+	1434:  {0x02}, // SQL Server Browser: list instances
+	1900:  []byte("M-SEARCH * HTTP/1.1\r\nHOST: 239.255.255.250:1900\r\nMAN: \"ssdp:discover\"\r\nMX: 1\r\nST: ssdp:all\r\n\r\n"),
+	4500:  append([]byte{0, 0, 0, 0}, ikeMainMode()...), // IKE over NAT-T: non-ESP marker first
+	5353:  dnsQuery("_services._dns-sd._udp.local.", dnsmessage.TypePTR),
+	11211: []byte("\x00\x01\x00\x00\x00\x01\x00\x00version\r\n"), // memcached UDP frame header + command
+}
+
+// probeUDP sends the port's probe and waits for any answer: a reply means
+// open, an ICMP port unreachable (reported by the kernel as a refused read
+// on a connected socket) means closed, and silence after a retry means
+// open|filtered. No raw sockets are needed. Like probe, it waits out the
+// scanner's backoff and retries resource errors until a socket is free. It
+// returns "" if ctx was cancelled first.
+func (ps *PortScanner) probeUDP(ctx context.Context, target string, port int, rtt *rttEstimator) string {
+	address := net.JoinHostPort(target, strconv.Itoa(port))
+	payload := udpProbes[port]
+
+	for {
+		if !ps.backoff.wait(ctx) {
+			return ""
+		}
+		conn, err := newDialer(ps.Brain, 0).DialContext(ctx, ps.network("udp", target), address)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ""
+			}
+			if !isResourceError(err) {
+				return engine.StateFiltered
+			}
+			if ps.backoff.fail() {
+				ps.Brain.Logf("[Scanner] Out of sockets (%v), backing off...", err)
+			}
+			continue
+		}
+		ps.backoff.succeed()
+		state := exchangeUDP(ctx, conn, payload, rtt)
+		conn.Close()
+		return state
+	}
+}
+
+// exchangeUDP sends payload up to twice (datagrams get lost) and classifies
+// what came back
+func exchangeUDP(ctx context.Context, conn net.Conn, payload []byte, rtt *rttEstimator) string {
+	buf := make([]byte, 1500)
+	for try := 0; try < 2; try++ {
+		start := time.Now()
+		if _, err := conn.Write(payload); err != nil {
+			if isRefused(err) {
+				return engine.StateClosed
+			}
+			return engine.StateFiltered
+		}
+		deadline := start.Add(rtt.timeout())
+		if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+			deadline = d
+		}
+		conn.SetReadDeadline(deadline)
+		_, err := conn.Read(buf)
+		switch {
+		case err == nil:
+			rtt.observe(time.Since(start))
+			return engine.StateOpen
+		case isRefused(err):
+			rtt.observe(time.Since(start))
+			return engine.StateClosed
+		case ctx.Err() != nil:
+			return ""
+		}
+	}
+	return engine.StateOpenFiltered
+}
+
+func dnsQuery(name string, qtype dnsmessage.Type) []byte {
+	msg := dnsmessage.Message{
+		Header: dnsmessage.Header{ID: 0x6f72},
+		Questions: []dnsmessage.Question{{
+			Name:  dnsmessage.MustNewName(name),
+			Type:  qtype,
+			Class: dnsmessage.ClassINET,
+		}},
+	}
+	b, err := msg.Pack()
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// ntpRequest is a 48 byte NTPv3 client request (LI 0, VN 3, mode 3)
+func ntpRequest() []byte {
+	b := make([]byte, 48)
+	b[0] = 0x1b
+	return b
+}
+
+// nbstatQuery asks a NetBIOS name service for its name table ("*" name)
+func nbstatQuery() []byte {
+	b := []byte{0x6f, 0x72, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x20}
+	b = append(b, "CKAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA"...)
+	return append(b, 0x00, 0x00, 0x21, 0x00, 0x01) // NBSTAT, IN
+}
+
+// snmpGetRequest is an SNMPv1 GetRequest for sysDescr.0 with community
+// "public". Agents with another community usually stay silent.
+func snmpGetRequest() []byte {
+	return []byte{
+		0x30, 0x29, // SEQUENCE
+		0x02, 0x01, 0x00, // version 1
+		0x04, 0x06, 'p', 'u', 'b', 'l', 'i', 'c', // community
+		0xa0, 0x1c, // GetRequest PDU
+		0x02, 0x04, 0x00, 0x00, 0x6f, 0x72, // request id
+		0x02, 0x01, 0x00, // error status
+		0x02, 0x01, 0x00, // error index
+		0x30, 0x0e, 0x30, 0x0c, // varbind list, varbind
+		0x06, 0x08, 0x2b, 0x06, 0x01, 0x02, 0x01, 0x01, 0x01, 0x00, // 1.3.6.1.2.1.1.1.0
+		0x05, 0x00, // NULL
+	}
+}
+
+// ikeMainMode is an IKEv1 Main Mode proposal (3DES, SHA1, PSK, MODP1024),
+// which most VPN gateways answer with their own proposal or a notification
+func ikeMainMode() []byte {
+	attrs := [][2]uint16{
+		{0x8001, 5},     // encryption: 3DES
+		{0x8002, 2},     // hash: SHA1
+		{0x8003, 1},     // authentication: pre-shared key
+		{0x8004, 2},     // group: MODP1024
+		{0x800b, 1},     // life type: seconds
+		{0x800c, 28800}, // life duration
+	}
+	transform := []byte{0, 0, 0, 0, 1, 1, 0, 0} // last, len, #1, KEY_IKE
+	for _, a := range attrs {
+		transform = binary.BigEndian.AppendUint16(transform, a[0])
+		transform = binary.BigEndian.AppendUint16(transform, a[1])
+	}
+	binary.BigEndian.PutUint16(transform[2:], uint16(len(transform)))
+
+	proposal := append([]byte{0, 0, 0, 0, 1, 1, 0, 1}, transform...) // last, len, #1, ISAKMP, no SPI, 1 transform
+	binary.BigEndian.PutUint16(proposal[2:], uint16(len(proposal)))
+
+	sa := append([]byte{0, 0, 0, 0, 0, 0, 0, 1, 0, 0, 0, 1}, proposal...) // DOI IPsec, situation identity only
+	binary.BigEndian.PutUint16(sa[2:], uint16(len(sa)))
+
+	header := []byte{
+		'g', 'o', 'r', 'e', 'c', 'o', 'n', 0, // initiator cookie
+		0, 0, 0, 0, 0, 0, 0, 0, // responder cookie
+		0x01, 0x10, 0x02, 0x00, // next payload SA, version 1.0, Main Mode, no flags
+		0, 0, 0, 0, // message id
+		0, 0, 0, 0, // length
+	}
+	binary.BigEndian.PutUint32(header[24:], uint32(len(header)+len(sa)))
+	return append(header, sa...)
+}
@@ -0,0 +1,160 @@
+// Package output writes engine events in machine-readable formats, so scan
+// results can be piped into other tools instead of scraping log text.
+//
+// Every format uses the same per-event schema as the findings store:
+//
+//	{"type": "PORT_OPEN", "target": "a.example.com", "time": "...", "payload": {"port": 443, "protocol": "tcp"}}
+//
+// where the payload shape is fixed per event type (see engine/payload.go).
+package output
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+
+	"gorecTool/internal/engine"
+)
+
+// Formats lists the supported --output values
+var Formats = []string{"json", "jsonl", "csv"}
+
+// Writer is an engine.Observer that renders events; call Close once the
+// engine is done so buffered formats (json) get written out.
+type Writer interface {
+	engine.Observer
+	Close() error
+}
+
+// New returns a Writer for the given format
+func New(format string, w io.Writer) (Writer, error) {
+	switch format {
+	case "json":
+		return &jsonWriter{w: w}, nil
+	case "jsonl":
+		return &jsonlWriter{enc: json.NewEncoder(w)}, nil
+	case "csv":
+		return newCSVWriter(w), nil
+	}
+	return nil, fmt.Errorf("unknown output format %q (use json, jsonl or csv)", format)
+}
+
+// WriteAll renders an already collected list of events (e.g. from the store)
+func WriteAll(format string, w io.Writer, events []engine.Event) error {
+	out, err := New(format, w)
+	if err != nil {
+		return err
+	}
+	for _, e := range events {
+		out.OnEvent(e)
+	}
+	return out.Close()
+}
+
+// jsonWriter buffers everything and writes one JSON array at the end
+type jsonWriter struct {
+	mu     sync.Mutex
+	w      io.Writer
+	events []engine.Event
+}
+
+func (j *jsonWriter) OnEvent(e engine.Event) {
+	j.mu.Lock()
+	j.events = append(j.events, e)
+	j.mu.Unlock()
+}
+
+func (j *jsonWriter) OnLog(message string) {}
+
+func (j *jsonWriter) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	events := j.events
+	if events == nil {
+		events = []engine.Event{} // "[]", not "null"
+	}
+	enc := json.NewEncoder(j.w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(events)
+}
+
+// jsonlWriter streams one JSON object per line as events arrive
+type jsonlWriter struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+	err error
+}
+
+func (j *jsonlWriter) OnEvent(e engine.Event) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if err := j.enc.Encode(e); err != nil && j.err == nil {
+		j.err = err
+	}
+}
+
+func (j *jsonlWriter) OnLog(message string) {}
+
+func (j *jsonlWriter) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.err
+}
+
+// csvHeader is the fixed column layout. "payload" holds the typed payload
+// as JSON so every event type fits the same columns.
+var csvHeader = []string{"time", "type", "target", "port", "summary", "payload"}
+
+// csvWriter streams one row per event
+type csvWriter struct {
+	mu  sync.Mutex
+	w   *csv.Writer
+	err error
+}
+
+func newCSVWriter(w io.Writer) *csvWriter {
+	c := &csvWriter{w: csv.NewWriter(w)}
+	c.write(csvHeader)
+	return c
+}
+
+func (c *csvWriter) OnEvent(e engine.Event) {
+	payload, err := json.Marshal(e.Payload)
+	if err != nil {
+		payload = []byte("null")
+	}
+	port := ""
+	if p, ok := e.Port(); ok {
+		port = strconv.Itoa(p)
+	}
+	c.write([]string{
+		e.Time.UTC().Format(time.RFC3339Nano),
+		string(e.Type),
+		e.Target,
+		port,
+		e.Payload.String(),
+		string(payload),
+	})
+}
+
+func (c *csvWriter) OnLog(message string) {}
+
+func (c *csvWriter) write(record []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.w.Write(record)
+	c.w.Flush() // Stream: rows show up as the engine processes them
+	if err := c.w.Error(); err != nil && c.err == nil {
+		c.err = err
+	}
+}
+
+func (c *csvWriter) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
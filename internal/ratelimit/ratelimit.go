@@ -0,0 +1,76 @@
+// Package ratelimit provides a small token bucket used to keep DNS queries
+// and probes under a fixed rate.
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Limiter hands out tokens at a steady rate with a fixed burst. A nil
+// *Limiter never blocks, so callers don't need to special-case "unlimited".
+type Limiter struct {
+	mu       sync.Mutex
+	interval time.Duration // time to earn one token
+	burst    float64
+	tokens   float64
+	last     time.Time
+}
+
+// New returns a Limiter allowing perSecond events per second on average and
+// up to burst at once. A perSecond of zero or less means no limit (nil).
+func New(perSecond float64, burst int) *Limiter {
+	if perSecond <= 0 {
+		return nil
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	return &Limiter{
+		interval: time.Duration(float64(time.Second) / perSecond),
+		burst:    float64(burst),
+		tokens:   float64(burst),
+		last:     time.Now(),
+	}
+}
+
+// Wait blocks until a token is available or ctx is done
+func (l *Limiter) Wait(ctx context.Context) error {
+	if l == nil {
+		return ctx.Err()
+	}
+	for {
+		delay := l.reserve()
+		if delay == 0 {
+			return nil
+		}
+		t := time.NewTimer(delay)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// reserve takes a token if one is available, otherwise it returns how long
+// until the next one is earned
+func (l *Limiter) reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.tokens += float64(now.Sub(l.last)) / float64(l.interval)
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+
+	if l.tokens >= 1 {
+		l.tokens--
+		return 0
+	}
+	return time.Duration((1 - l.tokens) * float64(l.interval))
+}
@@ -0,0 +1,228 @@
+// Package report renders a scan's findings as a self-contained HTML page or
+// a Markdown document for stakeholders.
+package report
+
+import (
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"gorecTool/internal/engine"
+)
+
+//go:embed templates/*
+var templates embed.FS
+
+// Formats lists the supported report formats
+var Formats = []string{"html", "md"}
+
+// Meta describes the scan the report is about
+type Meta struct {
+	ScanID   string
+	Target   string
+	Started  time.Time
+	Finished time.Time
+	Status   string
+}
+
+// Report is the view model both templates render
+type Report struct {
+	Meta        Meta
+	Generated   time.Time
+	Subdomains  []Subdomain
+	Hosts       []Host
+	AlertGroups []AlertGroup
+	AlertCount  int
+}
+
+type Subdomain struct {
+	Name      string
+	Alive     bool
+	Addresses []string
+	Sources   []string
+	Records   []engine.DNSRecord
+}
+
+// Host gathers everything found on one target
+type Host struct {
+	Name     string
+	PTR      []string // Reverse DNS names of a raw IP target
+	Ports    []engine.PortOpen
+	Web      []engine.HttpService
+	Services []engine.ServiceDetected
+	// Summaries total every TCP scan of the host, one per address family
+	// (IPv4 first); empty if none finished
+	Summaries []engine.PortScanSummary
+}
+
+type Alert struct {
+	Target string
+	engine.VulnFound
+}
+
+// AlertGroup holds the alerts of one severity, most urgent group first
+type AlertGroup struct {
+	Severity string
+	Alerts   []Alert
+}
+
+var severityOrder = []string{engine.SeverityHigh, engine.SeverityMedium, engine.SeverityLow, engine.SeverityInfo}
+
+// Build turns raw events into the report model
+func Build(meta Meta, events []engine.Event) *Report {
+	r := &Report{Meta: meta, Generated: time.Now()}
+
+	subs := make(map[string]Subdomain)
+	records := make(map[string][]engine.DNSRecord)
+	hosts := make(map[string]*Host)
+	host := func(name string) *Host {
+		if h, ok := hosts[name]; ok {
+			return h
+		}
+		h := &Host{Name: name}
+		hosts[name] = h
+		return h
+	}
+	alerts := make(map[string][]Alert)
+	seen := make(map[string]bool) // Drop repeated identical findings
+
+	for _, e := range events {
+		key := fmt.Sprintf("%s|%s|%v", e.Type, e.Target, e.Payload)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		switch p := e.Payload.(type) {
+		case engine.SubdomainFound:
+			subs[e.Target] = Subdomain{Name: e.Target, Alive: p.Alive, Addresses: p.Addresses, Sources: p.Sources}
+		case engine.DNSRecord:
+			records[e.Target] = append(records[e.Target], p)
+		case engine.PortOpen:
+			h := host(e.Target)
+			h.Ports = append(h.Ports, p)
+		case engine.HttpService:
+			h := host(e.Target)
+			h.Web = append(h.Web, p)
+		case engine.ServiceDetected:
+			h := host(e.Target)
+			h.Services = append(h.Services, p)
+		case engine.PortScanSummary:
+			if p.Protocol != "tcp" {
+				continue // Silent UDP ports would drown the firewall signal
+			}
+			// A host can be scanned more than once (quick, then deep), but
+			// IPv4 and IPv6 are separate hosts to a firewall: keep them apart
+			h := host(e.Target)
+			i := slices.IndexFunc(h.Summaries, func(s engine.PortScanSummary) bool { return s.Family == p.Family })
+			if i < 0 {
+				i = len(h.Summaries)
+				h.Summaries = append(h.Summaries, engine.PortScanSummary{Protocol: p.Protocol, IP: p.IP, Family: p.Family})
+			}
+			s := &h.Summaries[i]
+			s.Scanned += p.Scanned
+			s.Open += p.Open
+			s.Closed += p.Closed
+			s.Filtered += p.Filtered
+		case engine.VulnFound:
+			alerts[p.Severity] = append(alerts[p.Severity], Alert{Target: e.Target, VulnFound: p})
+			r.AlertCount++
+		}
+	}
+
+	for _, s := range subs {
+		s.Records = records[s.Name]
+		r.Subdomains = append(r.Subdomains, s)
+	}
+	sort.Slice(r.Subdomains, func(i, j int) bool { return r.Subdomains[i].Name < r.Subdomains[j].Name })
+
+	for _, h := range hosts {
+		for _, rec := range records[h.Name] {
+			if rec.Type == "PTR" {
+				h.PTR = append(h.PTR, rec.Value)
+			}
+		}
+		sort.Slice(h.Ports, func(i, j int) bool {
+			if h.Ports[i].Port != h.Ports[j].Port {
+				return h.Ports[i].Port < h.Ports[j].Port
+			}
+			return h.Ports[i].String() < h.Ports[j].String()
+		})
+		sort.Slice(h.Web, func(i, j int) bool { return h.Web[i].Port < h.Web[j].Port })
+		sort.Slice(h.Services, func(i, j int) bool { return h.Services[i].Port < h.Services[j].Port })
+		sort.Slice(h.Summaries, func(i, j int) bool { return h.Summaries[i].Family < h.Summaries[j].Family })
+		r.Hosts = append(r.Hosts, *h)
+	}
+	sort.Slice(r.Hosts, func(i, j int) bool { return r.Hosts[i].Name < r.Hosts[j].Name })
+
+	for _, sev := range severityOrder {
+		if len(alerts[sev]) > 0 {
+			r.AlertGroups = append(r.AlertGroups, AlertGroup{Severity: sev, Alerts: alerts[sev]})
+			delete(alerts, sev)
+		}
+	}
+	// Severities we don't know the rank of go last, sorted so the report
+	// comes out the same every time
+	var unranked []string
+	for sev := range alerts {
+		unranked = append(unranked, sev)
+	}
+	sort.Strings(unranked)
+	for _, sev := range unranked {
+		r.AlertGroups = append(r.AlertGroups, AlertGroup{Severity: sev, Alerts: alerts[sev]})
+	}
+	return r
+}
+
+var funcs = map[string]interface{}{
+	"join":      strings.Join,
+	"upper":     strings.ToUpper,
+	"pct":       func(f float64) string { return fmt.Sprintf("%.0f%%", 100*f) },
+	"transport": engine.Transport,
+	"date":      func(t time.Time) string { return t.Local().Format("2006-01-02 15:04:05") },
+	"ports": func(ports []engine.PortOpen) string {
+		var s []string
+		for _, p := range ports {
+			s = append(s, p.String())
+		}
+		return strings.Join(s, ", ")
+	},
+	"records": func(recs []engine.DNSRecord) []string {
+		var s []string
+		for _, r := range recs {
+			if r.Type != "A" && r.Type != "AAAA" { // Already in Addresses
+				s = append(s, r.String())
+			}
+		}
+		return s
+	},
+	// mdcell escapes text for a Markdown table cell
+	"mdcell": func(s string) string {
+		return strings.NewReplacer("|", `\|`, "\n", " ").Replace(s)
+	},
+}
+
+// Render writes the report in the given format ("html" or "md")
+func (r *Report) Render(format string, w io.Writer) error {
+	switch format {
+	case "html":
+		t, err := htmltemplate.New("report.html.tmpl").Funcs(funcs).ParseFS(templates, "templates/report.html.tmpl")
+		if err != nil {
+			return err
+		}
+		return t.Execute(w, r)
+	case "md", "markdown":
+		t, err := texttemplate.New("report.md.tmpl").Funcs(funcs).ParseFS(templates, "templates/report.md.tmpl")
+		if err != nil {
+			return err
+		}
+		return t.Execute(w, r)
+	}
+	return fmt.Errorf("unknown report format %q (use html or md)", format)
+}
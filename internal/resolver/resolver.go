@@ -0,0 +1,292 @@
+// Package resolver resolves hostnames through a pool of DNS servers with
+// round-robin selection, per-server rate limits and retries, instead of
+// leaning on the (easily rate limited) system resolver alone.
+package resolver
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"os"
+	"strings"
+	"sync/atomic"
+
+	"gorecTool/internal/ratelimit"
+)
+
+var (
+	// ErrNotFound means the name does not exist (NXDOMAIN) or has no address
+	ErrNotFound = errors.New("no such host")
+	// ErrServFail means the server could not answer; another try may work
+	ErrServFail = errors.New("server failure")
+)
+
+// Record types understood by Lookup
+const (
+	TypeA     = "A"
+	TypeAAAA  = "AAAA"
+	TypeCNAME = "CNAME"
+	TypeMX    = "MX"
+	TypeTXT   = "TXT"
+	TypeNS    = "NS"
+	TypePTR   = "PTR"
+)
+
+// RecordTypes lists every record type Lookup supports
+var RecordTypes = []string{TypeA, TypeAAAA, TypeCNAME, TypeMX, TypeTXT, TypeNS, TypePTR}
+
+// Resolver turns a hostname into its addresses, or into records of one type.
+// Lookup returns names without the trailing dot and MX records as
+// "preference host". A name without records of that type is ErrNotFound.
+// PTR lookups take an IP address as the name.
+type Resolver interface {
+	LookupHost(ctx context.Context, host string) ([]string, error)
+	Lookup(ctx context.Context, name, rtype string) ([]string, error)
+}
+
+// System uses the operating system's resolver
+type System struct{}
+
+func (System) LookupHost(ctx context.Context, host string) ([]string, error) {
+	return net.DefaultResolver.LookupHost(ctx, host)
+}
+
+func (System) Lookup(ctx context.Context, name, rtype string) ([]string, error) {
+	values, err := systemLookup(ctx, name, rtype)
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+		return nil, fmt.Errorf("%s %s: %w", name, rtype, ErrNotFound)
+	}
+	return values, err
+}
+
+func systemLookup(ctx context.Context, name, rtype string) ([]string, error) {
+	r := net.DefaultResolver
+	var out []string
+	switch rtype {
+	case TypeA, TypeAAAA:
+		network := "ip4"
+		if rtype == TypeAAAA {
+			network = "ip6"
+		}
+		ips, err := r.LookupIP(ctx, network, name)
+		if err != nil {
+			return nil, err
+		}
+		for _, ip := range ips {
+			out = append(out, ip.String())
+		}
+	case TypeCNAME:
+		// The system resolver only gives the end of the chain, and the
+		// name itself when there is no CNAME at all
+		cname, err := r.LookupCNAME(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		cname = strings.TrimSuffix(cname, ".")
+		if strings.EqualFold(cname, strings.TrimSuffix(name, ".")) {
+			return nil, fmt.Errorf("%s: %w", name, ErrNotFound)
+		}
+		out = append(out, cname)
+	case TypeMX:
+		mxs, err := r.LookupMX(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		for _, mx := range mxs {
+			out = append(out, fmt.Sprintf("%d %s", mx.Pref, strings.TrimSuffix(mx.Host, ".")))
+		}
+	case TypeTXT:
+		txts, err := r.LookupTXT(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		out = txts
+	case TypeNS:
+		nss, err := r.LookupNS(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		for _, ns := range nss {
+			out = append(out, strings.TrimSuffix(ns.Host, "."))
+		}
+	case TypePTR:
+		names, err := r.LookupAddr(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		for _, n := range names {
+			out = append(out, strings.TrimSuffix(n, "."))
+		}
+	default:
+		return nil, fmt.Errorf("unsupported record type %q", rtype)
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("%s %s: %w", name, rtype, ErrNotFound)
+	}
+	return out, nil
+}
+
+func (System) String() string { return "system" }
+
+// Temporary reports whether a lookup error is worth retrying, possibly on
+// another server (SERVFAIL, timeouts, network errors)
+func Temporary(err error) bool {
+	if err == nil || errors.Is(err, ErrNotFound) || errors.Is(err, context.Canceled) {
+		return false
+	}
+	if errors.Is(err, ErrServFail) {
+		return true
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return !dnsErr.IsNotFound && (dnsErr.IsTimeout || dnsErr.IsTemporary)
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// Pool spreads lookups over several resolvers in turn. Each resolver has
+// its own rate limit, and temporary failures are retried on the next one.
+type Pool struct {
+	// Retries is how many extra attempts a temporary failure gets
+	Retries int
+
+	members []member
+	next    uint32
+}
+
+type member struct {
+	Resolver
+	limit *ratelimit.Limiter
+}
+
+// NewPool builds a pool that sends at most perSecond queries per second to
+// each resolver (0 = unlimited)
+func NewPool(resolvers []Resolver, perSecond float64) *Pool {
+	p := &Pool{Retries: 2}
+	for _, r := range resolvers {
+		p.members = append(p.members, member{Resolver: r, limit: ratelimit.New(perSecond, 1)})
+	}
+	return p
+}
+
+// Len is the number of resolvers in the pool
+func (p *Pool) Len() int {
+	return len(p.members)
+}
+
+func (p *Pool) LookupHost(ctx context.Context, host string) ([]string, error) {
+	var addrs []string
+	err := p.Do(ctx, func(r Resolver) error {
+		var err error
+		addrs, err = r.LookupHost(ctx, host)
+		return err
+	})
+	return addrs, err
+}
+
+func (p *Pool) Lookup(ctx context.Context, name, rtype string) ([]string, error) {
+	var values []string
+	err := p.Do(ctx, func(r Resolver) error {
+		var err error
+		values, err = r.Lookup(ctx, name, rtype)
+		return err
+	})
+	return values, err
+}
+
+// Do runs fn against the next resolver in turn (after waiting for its rate
+// limit), retrying temporary failures on the following ones
+func (p *Pool) Do(ctx context.Context, fn func(r Resolver) error) error {
+	if len(p.members) == 0 {
+		return errors.New("resolver pool is empty")
+	}
+	var err error
+	for attempt := 0; attempt <= p.Retries; attempt++ {
+		m := p.members[(atomic.AddUint32(&p.next, 1)-1)%uint32(len(p.members))]
+		if werr := m.limit.Wait(ctx); werr != nil {
+			return werr
+		}
+		if err = fn(m.Resolver); !Temporary(err) || ctx.Err() != nil {
+			return err
+		}
+	}
+	return err
+}
+
+// canaryZone is a reserved domain (RFC 2606) without a wildcard record:
+// every random name under it must be NXDOMAIN
+const canaryZone = "example.com"
+
+// Verify asks every resolver for a random name that cannot exist and drops
+// the ones that return addresses for it. Such resolvers (NXDOMAIN
+// hijacking, "search assist" ISP servers) would make every brute force
+// guess look alive. Resolvers that fail to answer are kept: their errors
+// are retried like any other. It returns the dropped resolvers; call it
+// before the pool is used.
+func (p *Pool) Verify(ctx context.Context) []Resolver {
+	canary := fmt.Sprintf("gorecon-%08x.%s", rand.Uint32(), canaryZone)
+	var kept []member
+	var dropped []Resolver
+	for i, m := range p.members {
+		if err := m.limit.Wait(ctx); err != nil {
+			kept = append(kept, p.members[i:]...)
+			break
+		}
+		if addrs, err := m.LookupHost(ctx, canary); err == nil && len(addrs) > 0 {
+			dropped = append(dropped, m.Resolver)
+			continue
+		}
+		kept = append(kept, m)
+	}
+	p.members = kept
+	return dropped
+}
+
+// Parse turns resolver specs into Resolvers: "system" for the OS resolver,
+// otherwise an IP address with an optional port ("1.1.1.1", "[::1]:5353")
+// queried directly over UDP
+func Parse(specs []string) ([]Resolver, error) {
+	var out []Resolver
+	for _, spec := range specs {
+		spec = strings.TrimSpace(spec)
+		switch {
+		case spec == "":
+			continue
+		case spec == "system":
+			out = append(out, System{})
+			continue
+		}
+		u := NewUDP(spec)
+		host, _, err := net.SplitHostPort(u.Addr)
+		if err != nil || net.ParseIP(host) == nil {
+			return nil, fmt.Errorf("bad resolver %q: want an IP address, ip:port or \"system\"", spec)
+		}
+		out = append(out, u)
+	}
+	return out, nil
+}
+
+// LoadFile reads resolver specs, one per line (blank lines and # comments
+// are skipped)
+func LoadFile(filename string) ([]string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var specs []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			specs = append(specs, line)
+		}
+	}
+	return specs, scanner.Err()
+}
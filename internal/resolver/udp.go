@@ -0,0 +1,243 @@
+package resolver
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// UDP queries one DNS server directly. Truncated answers are retried over
+// TCP. Point Addr at a local fake server to test against canned answers.
+type UDP struct {
+	Addr    string        // host:port
+	Timeout time.Duration // Per query, default 2s
+}
+
+// NewUDP returns a UDP resolver for addr, adding port 53 if it has none
+func NewUDP(addr string) *UDP {
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(strings.Trim(addr, "[]"), "53")
+	}
+	return &UDP{Addr: addr}
+}
+
+func (u *UDP) String() string { return u.Addr }
+
+// LookupHost asks for A and AAAA records; CNAMEs are followed by the server
+func (u *UDP) LookupHost(ctx context.Context, host string) ([]string, error) {
+	var addrs []string
+	var firstErr error
+	for _, qtype := range []dnsmessage.Type{dnsmessage.TypeA, dnsmessage.TypeAAAA} {
+		msg, err := u.Exchange(ctx, host, qtype)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		for _, ans := range msg.Answers {
+			switch body := ans.Body.(type) {
+			case *dnsmessage.AResource:
+				addrs = append(addrs, net.IP(body.A[:]).String())
+			case *dnsmessage.AAAAResource:
+				addrs = append(addrs, net.IP(body.AAAA[:]).String())
+			}
+		}
+	}
+	if len(addrs) > 0 {
+		return addrs, nil
+	}
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return nil, fmt.Errorf("%s: %w", host, ErrNotFound)
+}
+
+var queryTypes = map[string]dnsmessage.Type{
+	TypeA:     dnsmessage.TypeA,
+	TypeAAAA:  dnsmessage.TypeAAAA,
+	TypeCNAME: dnsmessage.TypeCNAME,
+	TypeMX:    dnsmessage.TypeMX,
+	TypeTXT:   dnsmessage.TypeTXT,
+	TypeNS:    dnsmessage.TypeNS,
+	TypePTR:   dnsmessage.TypePTR,
+}
+
+func (u *UDP) Lookup(ctx context.Context, name, rtype string) ([]string, error) {
+	qtype, ok := queryTypes[rtype]
+	if !ok {
+		return nil, fmt.Errorf("unsupported record type %q", rtype)
+	}
+	query := name
+	if rtype == TypePTR {
+		arpa, err := reverseName(name)
+		if err != nil {
+			return nil, err
+		}
+		query = arpa
+	}
+	msg, err := u.Exchange(ctx, query, qtype)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only keep answers of the asked type: an A query also carries the
+	// CNAMEs that led to the address
+	var out []string
+	for _, ans := range msg.Answers {
+		if ans.Header.Type != qtype {
+			continue
+		}
+		switch body := ans.Body.(type) {
+		case *dnsmessage.AResource:
+			out = append(out, net.IP(body.A[:]).String())
+		case *dnsmessage.AAAAResource:
+			out = append(out, net.IP(body.AAAA[:]).String())
+		case *dnsmessage.CNAMEResource:
+			out = append(out, strings.TrimSuffix(body.CNAME.String(), "."))
+		case *dnsmessage.MXResource:
+			out = append(out, fmt.Sprintf("%d %s", body.Pref, strings.TrimSuffix(body.MX.String(), ".")))
+		case *dnsmessage.TXTResource:
+			out = append(out, strings.Join(body.TXT, ""))
+		case *dnsmessage.NSResource:
+			out = append(out, strings.TrimSuffix(body.NS.String(), "."))
+		case *dnsmessage.PTRResource:
+			out = append(out, strings.TrimSuffix(body.PTR.String(), "."))
+		}
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("%s %s: %w", name, rtype, ErrNotFound)
+	}
+	return out, nil
+}
+
+// Exchange sends one query and returns the server's answer. NXDOMAIN and
+// SERVFAIL come back as ErrNotFound and ErrServFail.
+func (u *UDP) Exchange(ctx context.Context, name string, qtype dnsmessage.Type) (*dnsmessage.Message, error) {
+	qname, err := dnsmessage.NewName(strings.TrimSuffix(name, ".") + ".")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	id := uint16(rand.Intn(1 << 16))
+	query := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: id, RecursionDesired: true},
+		Questions: []dnsmessage.Question{{Name: qname, Type: qtype, Class: dnsmessage.ClassINET}},
+	}
+	packed, err := query.Pack()
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := u.Timeout
+	if timeout <= 0 {
+		timeout = 2 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	msg, err := u.roundTrip(ctx, "udp", packed, id)
+	if err == nil && msg.Truncated {
+		msg, err = u.roundTrip(ctx, "tcp", packed, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch msg.RCode {
+	case dnsmessage.RCodeSuccess:
+		return msg, nil
+	case dnsmessage.RCodeNameError:
+		return nil, fmt.Errorf("%s: %w", name, ErrNotFound)
+	case dnsmessage.RCodeServerFailure:
+		return nil, fmt.Errorf("%s via %s: %w", name, u.Addr, ErrServFail)
+	}
+	return nil, fmt.Errorf("%s via %s: %s", name, u.Addr, msg.RCode)
+}
+
+func (u *UDP) roundTrip(ctx context.Context, network string, packed []byte, id uint16) (*dnsmessage.Message, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, network, u.Addr)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	// Unblock the read if ctx is cancelled before the deadline
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	if network == "tcp" {
+		// TCP messages carry a two byte length prefix
+		framed := make([]byte, 2+len(packed))
+		binary.BigEndian.PutUint16(framed, uint16(len(packed)))
+		copy(framed[2:], packed)
+		if _, err := conn.Write(framed); err != nil {
+			return nil, err
+		}
+		var size [2]byte
+		if _, err := io.ReadFull(conn, size[:]); err != nil {
+			return nil, err
+		}
+		buf := make([]byte, binary.BigEndian.Uint16(size[:]))
+		if _, err := io.ReadFull(conn, buf); err != nil {
+			return nil, err
+		}
+		return parseReply(buf, id)
+	}
+
+	if _, err := conn.Write(packed); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 65535)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		// Ignore stray or spoofed packets that don't answer our query
+		if msg, err := parseReply(buf[:n], id); err == nil {
+			return msg, nil
+		}
+	}
+}
+
+func parseReply(buf []byte, id uint16) (*dnsmessage.Message, error) {
+	var msg dnsmessage.Message
+	if err := msg.Unpack(buf); err != nil {
+		return nil, err
+	}
+	if !msg.Response || msg.ID != id {
+		return nil, fmt.Errorf("unexpected DNS message (id %d)", msg.ID)
+	}
+	return &msg, nil
+}
+
+// reverseName is the in-addr.arpa / ip6.arpa name of an IP address
+func reverseName(addr string) (string, error) {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return "", fmt.Errorf("PTR lookup of %q: not an IP address", addr)
+	}
+	var b strings.Builder
+	if v4 := ip.To4(); v4 != nil {
+		for i := len(v4) - 1; i >= 0; i-- {
+			fmt.Fprintf(&b, "%d.", v4[i])
+		}
+		b.WriteString("in-addr.arpa.")
+		return b.String(), nil
+	}
+	for i := len(ip) - 1; i >= 0; i-- {
+		fmt.Fprintf(&b, "%x.%x.", ip[i]&0x0f, ip[i]>>4)
+	}
+	b.WriteString("ip6.arpa.")
+	return b.String(), nil
+}
@@ -0,0 +1,205 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"net"
+	"slices"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// answerFunc builds the reply to one question. Returning ok == false drops
+// the query, as a server that never answers would.
+type answerFunc func(q dnsmessage.Question) (rcode dnsmessage.RCode, answers []dnsmessage.Resource, ok bool)
+
+// fakeDNS serves answer on a UDP socket on 127.0.0.1 and returns its address
+func fakeDNS(t *testing.T, answer answerFunc) string {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, from, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			var query dnsmessage.Message
+			if err := query.Unpack(buf[:n]); err != nil || len(query.Questions) != 1 {
+				continue
+			}
+			rcode, answers, ok := answer(query.Questions[0])
+			if !ok {
+				continue
+			}
+			reply := dnsmessage.Message{
+				Header:    dnsmessage.Header{ID: query.ID, Response: true, RCode: rcode},
+				Questions: query.Questions,
+				Answers:   answers,
+			}
+			packed, err := reply.Pack()
+			if err != nil {
+				t.Errorf("packing reply: %v", err)
+				return
+			}
+			conn.WriteTo(packed, from)
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func rr(name string, body dnsmessage.ResourceBody) dnsmessage.Resource {
+	return dnsmessage.Resource{
+		Header: dnsmessage.ResourceHeader{Name: dnsmessage.MustNewName(name), Class: dnsmessage.ClassINET, TTL: 60},
+		Body:   body,
+	}
+}
+
+// zone answers www.example.com (a CNAME to web.example.com, with its
+// addresses) and web.example.com, and NXDOMAIN for everything else
+func zone(q dnsmessage.Question) (dnsmessage.RCode, []dnsmessage.Resource, bool) {
+	cname := rr("www.example.com.", &dnsmessage.CNAMEResource{CNAME: dnsmessage.MustNewName("web.example.com.")})
+	a := rr("web.example.com.", &dnsmessage.AResource{A: [4]byte{192, 0, 2, 10}})
+	aaaa := rr("web.example.com.", &dnsmessage.AAAAResource{AAAA: [16]byte{0x20, 0x01, 0x0d, 0xb8, 15: 0x10}})
+
+	switch q.Name.String() {
+	case "www.example.com.":
+		switch q.Type {
+		case dnsmessage.TypeA:
+			return dnsmessage.RCodeSuccess, []dnsmessage.Resource{cname, a}, true
+		case dnsmessage.TypeAAAA:
+			return dnsmessage.RCodeSuccess, []dnsmessage.Resource{cname, aaaa}, true
+		case dnsmessage.TypeCNAME:
+			return dnsmessage.RCodeSuccess, []dnsmessage.Resource{cname}, true
+		}
+		return dnsmessage.RCodeSuccess, nil, true
+	case "web.example.com.":
+		switch q.Type {
+		case dnsmessage.TypeA:
+			return dnsmessage.RCodeSuccess, []dnsmessage.Resource{a}, true
+		case dnsmessage.TypeAAAA:
+			return dnsmessage.RCodeSuccess, []dnsmessage.Resource{aaaa}, true
+		}
+		return dnsmessage.RCodeSuccess, nil, true
+	}
+	return dnsmessage.RCodeNameError, nil, true
+}
+
+// liar answers every A query with the same address, like an ISP resolver
+// hijacking NXDOMAIN
+func liar(q dnsmessage.Question) (dnsmessage.RCode, []dnsmessage.Resource, bool) {
+	if q.Type != dnsmessage.TypeA {
+		return dnsmessage.RCodeSuccess, nil, true
+	}
+	return dnsmessage.RCodeSuccess, []dnsmessage.Resource{rr(q.Name.String(), &dnsmessage.AResource{A: [4]byte{198, 51, 100, 1}})}, true
+}
+
+func TestUDPLookupHost(t *testing.T) {
+	u := NewUDP(fakeDNS(t, zone))
+	addrs, err := u.LookupHost(context.Background(), "www.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"192.0.2.10", "2001:db8::10"}; !slices.Equal(addrs, want) {
+		t.Errorf("LookupHost = %q, want %q", addrs, want)
+	}
+}
+
+func TestUDPLookup(t *testing.T) {
+	u := NewUDP(fakeDNS(t, zone))
+	tests := []struct {
+		name, rtype string
+		want        []string
+	}{
+		// The CNAME that led to the address is not an A record
+		{"www.example.com", TypeA, []string{"192.0.2.10"}},
+		{"www.example.com", TypeAAAA, []string{"2001:db8::10"}},
+		{"www.example.com", TypeCNAME, []string{"web.example.com"}},
+		{"web.example.com.", TypeA, []string{"192.0.2.10"}},
+	}
+	for _, tt := range tests {
+		got, err := u.Lookup(context.Background(), tt.name, tt.rtype)
+		if err != nil {
+			t.Errorf("Lookup(%s, %s): %v", tt.name, tt.rtype, err)
+			continue
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("Lookup(%s, %s) = %q, want %q", tt.name, tt.rtype, got, tt.want)
+		}
+	}
+
+	// No record of the type is ErrNotFound too
+	if _, err := u.Lookup(context.Background(), "web.example.com", TypeMX); !errors.Is(err, ErrNotFound) {
+		t.Errorf("MX of a name without one: err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestUDPNXDomain(t *testing.T) {
+	u := NewUDP(fakeDNS(t, zone))
+	_, err := u.LookupHost(context.Background(), "missing.example.com")
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("err = %v, want ErrNotFound", err)
+	}
+	if Temporary(err) {
+		t.Error("NXDOMAIN is reported as temporary")
+	}
+}
+
+func TestUDPTimeout(t *testing.T) {
+	silent := func(dnsmessage.Question) (dnsmessage.RCode, []dnsmessage.Resource, bool) {
+		return 0, nil, false
+	}
+	u := NewUDP(fakeDNS(t, silent))
+	u.Timeout = 100 * time.Millisecond
+
+	start := time.Now()
+	_, err := u.Lookup(context.Background(), "www.example.com", TypeA)
+	if err == nil {
+		t.Fatal("no error from a server that never answers")
+	}
+	if !Temporary(err) {
+		t.Errorf("timeout %v is not temporary, the pool would not retry it", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("lookup took %v with a 100ms timeout", elapsed)
+	}
+}
+
+func TestPoolRetriesServFail(t *testing.T) {
+	servfail := func(dnsmessage.Question) (dnsmessage.RCode, []dnsmessage.Resource, bool) {
+		return dnsmessage.RCodeServerFailure, nil, true
+	}
+	pool := NewPool([]Resolver{NewUDP(fakeDNS(t, servfail)), NewUDP(fakeDNS(t, zone))}, 0)
+	for i := 0; i < 2; i++ { // Whichever server comes first
+		if _, err := pool.Lookup(context.Background(), "web.example.com", TypeA); err != nil {
+			t.Errorf("lookup %d: %v", i, err)
+		}
+	}
+}
+
+func TestPoolVerifyDropsLiar(t *testing.T) {
+	honest := NewUDP(fakeDNS(t, zone))
+	lying := NewUDP(fakeDNS(t, liar))
+	pool := NewPool([]Resolver{lying, honest}, 0)
+
+	dropped := pool.Verify(context.Background())
+	if len(dropped) != 1 || dropped[0] != lying {
+		t.Fatalf("dropped %v, want only %v", dropped, lying)
+	}
+	if pool.Len() != 1 {
+		t.Fatalf("pool has %d resolvers left, want 1", pool.Len())
+	}
+	// Every query now goes to the honest server
+	for i := 0; i < 3; i++ {
+		if _, err := pool.LookupHost(context.Background(), "missing.example.com"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("lookup %d: err = %v, want ErrNotFound", i, err)
+		}
+	}
+}
@@ -0,0 +1,216 @@
+// Package rules loads declarative rule files and turns them into engine.Rules,
+// so the autonomous pipeline can be tuned without recompiling.
+package rules
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"gorecTool/internal/engine"
+)
+
+//go:embed default.yaml
+var defaultRules []byte
+
+// Spec is one rule as written in a rule file
+type Spec struct {
+	Name   string           `yaml:"name"`
+	On     engine.EventType `yaml:"on"`
+	Match  Match            `yaml:"match"`
+	Action string           `yaml:"action"`
+}
+
+// Match narrows down which events of the given type trigger the rule.
+// Empty fields match everything.
+type Match struct {
+	Target      string   `yaml:"target"`       // Glob, e.g. "*.example.com"
+	TargetRegex string   `yaml:"target_regex"` // Regular expression
+	Ports       []int    `yaml:"ports"`
+	Protocol    string   `yaml:"protocol"`    // Transport of the port, "tcp" or "udp"
+	Tech        string   `yaml:"tech"`        // Case-insensitive substring of a tech or Server header
+	Services    []string `yaml:"services"`    // SERVICE_DETECTED service names, e.g. [http, ssh]
+	RecordType  string   `yaml:"record_type"` // DNS_RECORD type, e.g. "CNAME"
+	Value       string   `yaml:"value"`       // Glob on the DNS_RECORD value, e.g. "*.cloudfront.net"
+}
+
+type file struct {
+	Rules []Spec `yaml:"rules"`
+}
+
+// Default returns the built-in rule set (Service-Detection, Web-Discovery,
+// Context-Fuzzer and Takeover-Check)
+func Default() []Spec {
+	specs, err := Parse(defaultRules)
+	if err != nil {
+		panic(fmt.Sprintf("rules: bad embedded default.yaml: %v", err))
+	}
+	return specs
+}
+
+// Load reads a YAML rule file from disk
+func Load(filename string) ([]Spec, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	specs, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return specs, nil
+}
+
+// Parse decodes a YAML rule document. Unknown keys are errors: a typo in
+// a match field would otherwise leave a rule that fires on every event.
+func Parse(data []byte) ([]Spec, error) {
+	var f file
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&f); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	return f.Rules, nil
+}
+
+// Build validates the specs and binds each one to an action by name
+func Build(specs []Spec, actions map[string]func(context.Context, engine.Event)) ([]engine.Rule, error) {
+	var out []engine.Rule
+	for i, spec := range specs {
+		if spec.Name == "" {
+			spec.Name = fmt.Sprintf("rule-%d", i+1)
+		}
+		if !spec.On.Known() {
+			return nil, fmt.Errorf("rule %q: unknown event type %q in 'on'", spec.Name, spec.On)
+		}
+		action, ok := actions[spec.Action]
+		if !ok {
+			return nil, fmt.Errorf("rule %q: unknown action %q (available: %s)", spec.Name, spec.Action, actionNames(actions))
+		}
+		if spec.Match.Target != "" {
+			if _, err := path.Match(spec.Match.Target, ""); err != nil {
+				return nil, fmt.Errorf("rule %q: bad target glob: %w", spec.Name, err)
+			}
+		}
+		if spec.Match.Value != "" {
+			if _, err := path.Match(spec.Match.Value, ""); err != nil {
+				return nil, fmt.Errorf("rule %q: bad value glob: %w", spec.Name, err)
+			}
+		}
+		var re *regexp.Regexp
+		if spec.Match.TargetRegex != "" {
+			var err error
+			if re, err = regexp.Compile(spec.Match.TargetRegex); err != nil {
+				return nil, fmt.Errorf("rule %q: bad target_regex: %w", spec.Name, err)
+			}
+		}
+
+		s := spec
+		out = append(out, engine.Rule{
+			Name:      s.Name,
+			Condition: func(e engine.Event) bool { return s.matches(e, re) },
+			Action:    action,
+		})
+	}
+	return out, nil
+}
+
+func (s Spec) matches(e engine.Event, re *regexp.Regexp) bool {
+	if e.Type != s.On {
+		return false
+	}
+	m := s.Match
+	if m.Target != "" {
+		if ok, _ := path.Match(m.Target, e.Target); !ok {
+			return false
+		}
+	}
+	if re != nil && !re.MatchString(e.Target) {
+		return false
+	}
+	if len(m.Ports) > 0 {
+		port, ok := e.Port()
+		if !ok || !containsInt(m.Ports, port) {
+			return false
+		}
+	}
+	if m.Protocol != "" && !strings.EqualFold(e.Protocol(), m.Protocol) {
+		return false
+	}
+	if m.Tech != "" && !hasTech(e, m.Tech) {
+		return false
+	}
+	if len(m.Services) > 0 && !hasService(e, m.Services) {
+		return false
+	}
+	if m.RecordType != "" || m.Value != "" {
+		rec, ok := e.Payload.(engine.DNSRecord)
+		if !ok {
+			return false
+		}
+		if m.RecordType != "" && !strings.EqualFold(rec.Type, m.RecordType) {
+			return false
+		}
+		if m.Value != "" {
+			if ok, _ := path.Match(strings.ToLower(m.Value), strings.ToLower(rec.Value)); !ok {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func hasService(e engine.Event, want []string) bool {
+	svc, ok := e.Payload.(engine.ServiceDetected)
+	if !ok {
+		return false
+	}
+	for _, w := range want {
+		if strings.EqualFold(svc.Service, w) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasTech(e engine.Event, want string) bool {
+	svc, ok := e.Payload.(engine.HttpService)
+	if !ok {
+		return false
+	}
+	want = strings.ToLower(want)
+	for _, t := range append([]string{svc.Server}, svc.Tech...) {
+		if strings.Contains(strings.ToLower(t), want) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsInt(list []int, v int) bool {
+	for _, x := range list {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+func actionNames(actions map[string]func(context.Context, engine.Event)) string {
+	var names []string
+	for name := range actions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
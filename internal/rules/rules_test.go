@@ -0,0 +1,116 @@
+package rules
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"gorecTool/internal/engine"
+)
+
+var noop = map[string]func(context.Context, engine.Event){
+	"detect-service": func(context.Context, engine.Event) {},
+	"http-analyze":   func(context.Context, engine.Event) {},
+}
+
+func TestDefaultBuilds(t *testing.T) {
+	actions := map[string]func(context.Context, engine.Event){
+		"detect-service": nil, "http-analyze": nil, "hunt-files": nil, "takeover-check": nil,
+	}
+	rules, err := Build(Default(), actions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != 4 {
+		t.Errorf("%d default rules, want 4", len(rules))
+	}
+}
+
+func TestParseUnknownField(t *testing.T) {
+	for _, doc := range []string{
+		"rules:\n  - on: PORT_OPEN\n    match:\n      prots: [80]\n    action: detect-service\n",
+		"rules:\n  - on: HTTP_SERVICE\n    match:\n      tecth: php\n    action: http-analyze\n",
+		"rules:\n  - on: PORT_OPEN\n    acton: detect-service\n",
+		"rule:\n  - on: PORT_OPEN\n",
+	} {
+		if _, err := Parse([]byte(doc)); err == nil {
+			t.Errorf("no error for\n%s", doc)
+		}
+	}
+	if specs, err := Parse(nil); err != nil || len(specs) != 0 {
+		t.Errorf("empty document: %v, %v", specs, err)
+	}
+}
+
+func TestBuildErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		spec Spec
+		want string // Substring of the error
+	}{
+		{"unknown action", Spec{On: engine.EventPortOpen, Action: "nuke"}, "unknown action"},
+		{"missing action", Spec{On: engine.EventPortOpen}, "unknown action"},
+		{"unknown event", Spec{On: "PORT_OPENED", Action: "detect-service"}, "unknown event type"},
+		{"bad glob", Spec{On: engine.EventPortOpen, Action: "detect-service", Match: Match{Target: "[a-"}}, "bad target glob"},
+		{"bad regex", Spec{On: engine.EventPortOpen, Action: "detect-service", Match: Match{TargetRegex: "("}}, "bad target_regex"},
+		{"bad value glob", Spec{On: engine.EventDNSRecord, Action: "detect-service", Match: Match{Value: "[x"}}, "bad value glob"},
+	}
+	for _, tt := range tests {
+		_, err := Build([]Spec{tt.spec}, noop)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	portOpen := func(port int, protocol string) engine.Event {
+		return engine.Event{Type: engine.EventPortOpen, Target: "www.example.com", Payload: engine.PortOpen{Port: port, Protocol: protocol}}
+	}
+	service := func(name string) engine.Event {
+		return engine.Event{Type: engine.EventServiceFound, Target: "www.example.com", Payload: engine.ServiceDetected{Port: 22, Protocol: "tcp", Service: name}}
+	}
+	web := engine.Event{Type: engine.EventHttpService, Target: "www.example.com",
+		Payload: engine.HttpService{Port: 80, Server: "nginx/1.25", Tech: []string{"PHP", "WordPress"}}}
+	record := func(rtype, value string) engine.Event {
+		return engine.Event{Type: engine.EventDNSRecord, Target: "cdn.example.com", Payload: engine.DNSRecord{Type: rtype, Value: value}}
+	}
+
+	tests := []struct {
+		name  string
+		on    engine.EventType
+		match Match
+		event engine.Event
+		want  bool
+	}{
+		{"no match fields", engine.EventPortOpen, Match{}, portOpen(80, "tcp"), true},
+		{"other event type", engine.EventHttpService, Match{}, portOpen(80, "tcp"), false},
+		{"port listed", engine.EventPortOpen, Match{Ports: []int{80, 443}}, portOpen(443, "tcp"), true},
+		{"port not listed", engine.EventPortOpen, Match{Ports: []int{80, 443}}, portOpen(8080, "tcp"), false},
+		{"protocol", engine.EventPortOpen, Match{Protocol: "tcp"}, portOpen(53, "tcp"), true},
+		{"other protocol", engine.EventPortOpen, Match{Protocol: "tcp"}, portOpen(53, "udp"), false},
+		{"target glob", engine.EventPortOpen, Match{Target: "*.example.com"}, portOpen(80, "tcp"), true},
+		{"target glob miss", engine.EventPortOpen, Match{Target: "*.example.org"}, portOpen(80, "tcp"), false},
+		{"target regex", engine.EventPortOpen, Match{TargetRegex: `^www\.`}, portOpen(80, "tcp"), true},
+		{"tech", engine.EventHttpService, Match{Tech: "wordpress"}, web, true},
+		{"tech from Server header", engine.EventHttpService, Match{Tech: "NGINX"}, web, true},
+		{"tech missing", engine.EventHttpService, Match{Tech: "Apache"}, web, false},
+		{"tech on a payload without one", engine.EventPortOpen, Match{Tech: "php"}, portOpen(80, "tcp"), false},
+		{"service", engine.EventServiceFound, Match{Services: []string{"http", "ssh"}}, service("ssh"), true},
+		{"other service", engine.EventServiceFound, Match{Services: []string{"http"}}, service("ssh"), false},
+		{"record type", engine.EventDNSRecord, Match{RecordType: "cname"}, record("CNAME", "x.cloudfront.net"), true},
+		{"other record type", engine.EventDNSRecord, Match{RecordType: "CNAME"}, record("A", "192.0.2.1"), false},
+		{"record value", engine.EventDNSRecord, Match{RecordType: "CNAME", Value: "*.cloudfront.net"}, record("CNAME", "D111.CloudFront.net"), true},
+		{"record value miss", engine.EventDNSRecord, Match{Value: "*.cloudfront.net"}, record("CNAME", "x.fastly.net"), false},
+	}
+	for _, tt := range tests {
+		rules, err := Build([]Spec{{Name: tt.name, On: tt.on, Match: tt.match, Action: "detect-service"}}, noop)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got := rules[0].Condition(tt.event); got != tt.want {
+			t.Errorf("%s: matched %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
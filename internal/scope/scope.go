@@ -0,0 +1,152 @@
+// Package scope describes what an engagement authorizes us to touch: the
+// domains and address blocks in scope, minus explicit exclusions. The
+// engine checks it before any module acts on a target.
+package scope
+
+import (
+	"fmt"
+	"net/netip"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"gorecTool/internal/targets"
+)
+
+// Scope is a parsed scope file. Entries are domains ("example.com" covers
+// the domain and every subdomain, "*.example.com" only the subdomains),
+// IP addresses, CIDR blocks and IP ranges.
+type Scope struct {
+	include entries
+	exclude entries
+}
+
+type entries struct {
+	domains []string // "example.com", or ".example.com" for subdomains only
+	ranges  []addrRange
+}
+
+type addrRange struct{ from, to netip.Addr }
+
+// file is the on-disk shape of a scope file
+type file struct {
+	Include []string `yaml:"include"`
+	Exclude []string `yaml:"exclude"`
+}
+
+// Load reads a YAML scope file:
+//
+//	include:
+//	  - example.com
+//	  - 10.0.0.0/24
+//	exclude:
+//	  - vpn.example.com
+//	  - 10.0.0.1
+func Load(filename string) (*Scope, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	s, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return s, nil
+}
+
+// Parse decodes a YAML scope document
+func Parse(data []byte) (*Scope, error) {
+	var f file
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, err
+	}
+	return New(f.Include, f.Exclude)
+}
+
+// New builds a scope from include and exclude entries. An empty include
+// list is an error: a scope that allows nothing is always a mistake.
+func New(include, exclude []string) (*Scope, error) {
+	s := &Scope{}
+	var err error
+	if s.include, err = parseEntries(include); err != nil {
+		return nil, fmt.Errorf("include: %w", err)
+	}
+	if s.exclude, err = parseEntries(exclude); err != nil {
+		return nil, fmt.Errorf("exclude: %w", err)
+	}
+	if len(s.include.domains) == 0 && len(s.include.ranges) == 0 {
+		return nil, fmt.Errorf("include is empty, nothing would be in scope")
+	}
+	return s, nil
+}
+
+func parseEntries(specs []string) (entries, error) {
+	var e entries
+	for _, spec := range specs {
+		spec = strings.ToLower(strings.TrimSpace(spec))
+		if spec == "" {
+			continue
+		}
+		if targets.IsAddress(spec) {
+			from, to, err := targets.Bounds(spec)
+			if err != nil {
+				return e, err
+			}
+			e.ranges = append(e.ranges, addrRange{from, to})
+			continue
+		}
+		name := strings.TrimSuffix(spec, ".")
+		if rest, ok := strings.CutPrefix(name, "*."); ok {
+			name = "." + rest
+		}
+		if name == "" || name == "." || strings.ContainsAny(name, "*/ :") {
+			return e, fmt.Errorf("bad entry %q", spec)
+		}
+		e.domains = append(e.domains, name)
+	}
+	return e, nil
+}
+
+// InScope reports whether target (a hostname or an IP address) is covered
+// by an include entry and by no exclude entry. Hostnames are matched by
+// name only; use Excluded on the addresses they resolve to.
+func (s *Scope) InScope(target string) bool {
+	return s.include.match(target) && !s.exclude.match(target)
+}
+
+// Excluded reports whether target is covered by an exclude entry
+func (s *Scope) Excluded(target string) bool {
+	return s.exclude.match(target)
+}
+
+func (e entries) match(target string) bool {
+	target = strings.ToLower(strings.TrimSuffix(target, "."))
+	if ip, err := netip.ParseAddr(target); err == nil {
+		ip = ip.Unmap()
+		for _, r := range e.ranges {
+			if !ip.Less(r.from) && !r.to.Less(ip) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, d := range e.domains {
+		if strings.HasPrefix(d, ".") {
+			if strings.HasSuffix(target, d) {
+				return true
+			}
+			continue
+		}
+		// A label boundary is required: "badexample.com" is not under "example.com"
+		if target == d || strings.HasSuffix(target, "."+d) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Scope) String() string {
+	return fmt.Sprintf("%d domains, %d address blocks, %d exclusions",
+		len(s.include.domains), len(s.include.ranges), len(s.exclude.domains)+len(s.exclude.ranges))
+}
@@ -0,0 +1,101 @@
+package scope
+
+import "testing"
+
+func TestInScope(t *testing.T) {
+	s, err := New(
+		[]string{"example.com", "*.corp.test", "10.0.0.0/24", "192.168.1.10-20", "2001:db8::/64"},
+		[]string{"vpn.example.com", "10.0.0.1", "192.168.1.15"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		target string
+		want   bool
+	}{
+		// Domains cover themselves and their subdomains
+		{"example.com", true},
+		{"www.example.com", true},
+		{"a.b.example.com", true},
+		{"EXAMPLE.COM.", true},
+		// Suffix matches need a label boundary
+		{"badexample.com", false},
+		{"example.com.evil.net", false},
+		{"example.org", false},
+		// "*." only covers subdomains
+		{"corp.test", false},
+		{"mail.corp.test", true},
+		{"notcorp.test", false},
+		// CIDR blocks and ranges
+		{"10.0.0.0", true},
+		{"10.0.0.255", true},
+		{"10.0.1.0", false},
+		{"192.168.1.10", true},
+		{"192.168.1.20", true},
+		{"192.168.1.21", false},
+		{"192.168.1.9", false},
+		{"::ffff:10.0.0.5", true},
+		{"2001:db8::1", true},
+		{"2001:db8:0:1::1", false},
+		// Exclusions win over includes
+		{"vpn.example.com", false},
+		{"host.vpn.example.com", false},
+		{"10.0.0.1", false},
+		{"192.168.1.15", false},
+	}
+	for _, tt := range tests {
+		if got := s.InScope(tt.target); got != tt.want {
+			t.Errorf("InScope(%q) = %v, want %v", tt.target, got, tt.want)
+		}
+	}
+}
+
+func TestExcluded(t *testing.T) {
+	s, err := New([]string{"example.com"}, []string{"*.dev.example.com", "10.0.0.0/30", "10.1.0.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		target string
+		want   bool
+	}{
+		{"api.dev.example.com", true},
+		{"dev.example.com", false},
+		{"www.example.com", false},
+		{"10.0.0.3", true},
+		{"10.0.0.4", false},
+		{"10.1.0.1", true},
+		{"::ffff:10.1.0.1", true},
+		{"10.1.0.2", false},
+	}
+	for _, tt := range tests {
+		if got := s.Excluded(tt.target); got != tt.want {
+			t.Errorf("Excluded(%q) = %v, want %v", tt.target, got, tt.want)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		doc     string
+		wantErr bool
+	}{
+		{"valid", "include:\n  - example.com\n  - 10.0.0.0/8\nexclude:\n  - 10.0.0.1\n", false},
+		{"empty include", "exclude:\n  - 10.0.0.1\n", true},
+		{"bad CIDR", "include:\n  - 10.0.0.0/33\n", true},
+		{"bad range", "include:\n  - 10.0.0.9-1\n", true},
+		{"bad domain", "include:\n  - exa mple.com\n", true},
+		{"bare wildcard", "include:\n  - \"*.\"\n", true},
+		{"not yaml", "include: [", true},
+	}
+	for _, tt := range tests {
+		_, err := Parse([]byte(tt.doc))
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: Parse error = %v, want error %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
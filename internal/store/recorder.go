@@ -0,0 +1,109 @@
+package store
+
+import (
+	"sync"
+	"time"
+
+	"gorecTool/internal/engine"
+)
+
+// Recorder flushes its buffer every recordBatch events or every
+// recordInterval, whichever comes first
+const (
+	recordBatch    = 256
+	recordInterval = time.Second
+)
+
+// Recorder is an engine.Observer that writes every event into a scan. The
+// engine loop only appends to a buffer; a background goroutine writes it
+// out in batches, one transaction each. Close flushes what is left.
+type Recorder struct {
+	Store  *Store
+	ScanID string
+
+	mu      sync.Mutex
+	pending []engine.Event
+	err     error // first write error, if any
+
+	full chan struct{} // pending reached recordBatch
+	done chan struct{} // closed by Close
+	wg   sync.WaitGroup
+}
+
+func NewRecorder(s *Store, scanID string) *Recorder {
+	r := &Recorder{
+		Store:  s,
+		ScanID: scanID,
+		full:   make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+	r.wg.Add(1)
+	go r.run()
+	return r
+}
+
+func (r *Recorder) OnEvent(e engine.Event) {
+	r.mu.Lock()
+	r.pending = append(r.pending, e)
+	n := len(r.pending)
+	r.mu.Unlock()
+	if n >= recordBatch {
+		select {
+		case r.full <- struct{}{}:
+		default: // A flush is already due
+		}
+	}
+}
+
+func (r *Recorder) OnLog(message string) {}
+
+// run flushes the buffer when it fills up or the interval ticks, until Close
+func (r *Recorder) run() {
+	defer r.wg.Done()
+	ticker := time.NewTicker(recordInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.full:
+		case <-ticker.C:
+		case <-r.done:
+			r.flush()
+			return
+		}
+		r.flush()
+	}
+}
+
+func (r *Recorder) flush() {
+	r.mu.Lock()
+	batch := r.pending
+	r.pending = nil
+	r.mu.Unlock()
+
+	if err := r.Store.RecordBatch(r.ScanID, batch); err != nil {
+		r.mu.Lock()
+		if r.err == nil {
+			r.err = err
+		}
+		r.mu.Unlock()
+	}
+}
+
+// Close writes the events still buffered and stops the background writer.
+// Call it once the engine is done, before FinishScan.
+func (r *Recorder) Close() error {
+	select {
+	case <-r.done:
+	default:
+		close(r.done)
+	}
+	r.wg.Wait()
+	return r.Err()
+}
+
+// Err returns the first error hit while recording
+func (r *Recorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
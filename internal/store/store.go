@@ -0,0 +1,255 @@
+// Package store persists every engine.Event in an embedded bbolt database,
+// grouped by scan, so results survive the process and can be compared later.
+package store
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"gorecTool/internal/engine"
+)
+
+var (
+	scansBucket  = []byte("scans")  // scan ID -> Scan (JSON)
+	eventsBucket = []byte("events") // scan ID -> nested bucket of events
+)
+
+// ErrNotFound is returned for an unknown scan ID
+var ErrNotFound = errors.New("scan not found")
+
+// Scan statuses
+const (
+	StatusRunning     = "running"
+	StatusComplete    = "complete"
+	StatusInterrupted = "interrupted"
+)
+
+// Scan is the metadata of one `gorecon scan` run
+type Scan struct {
+	ID       string    `json:"id"`
+	Target   string    `json:"target"`
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished,omitempty"`
+	Status   string    `json:"status"`
+	Events   int       `json:"events"`
+}
+
+// Store is the findings database
+type Store struct {
+	db *bolt.DB
+}
+
+// DefaultPath is ~/.gorecon/gorecon.db
+func DefaultPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "gorecon.db"
+	}
+	return filepath.Join(home, ".gorecon", "gorecon.db")
+}
+
+// Open opens (or creates) the database at path
+func Open(path string) (*Store, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, err
+	}
+	// Only one process can hold the file; fail fast instead of hanging
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", path, err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists(scansBucket); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucketIfNotExists(eventsBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &Store{db: db}, nil
+}
+
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// CreateScan registers a new running scan and returns it
+func (s *Store) CreateScan(target string) (Scan, error) {
+	now := time.Now().UTC()
+	scan := Scan{
+		ID:      newScanID(now),
+		Target:  target,
+		Started: now,
+		Status:  StatusRunning,
+	}
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		if _, err := tx.Bucket(eventsBucket).CreateBucket([]byte(scan.ID)); err != nil {
+			return err
+		}
+		return putScan(tx, scan)
+	})
+	return scan, err
+}
+
+// FinishScan stamps the end time, final status and event count of a scan
+func (s *Store) FinishScan(id, status string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		scan, err := getScan(tx, id)
+		if err != nil {
+			return err
+		}
+		if b := tx.Bucket(eventsBucket).Bucket([]byte(id)); b != nil {
+			scan.Events = b.Stats().KeyN
+		}
+		scan.Finished = time.Now().UTC()
+		scan.Status = status
+		return putScan(tx, scan)
+	})
+}
+
+// Record appends one event to a scan
+func (s *Store) Record(id string, e engine.Event) error {
+	return s.RecordBatch(id, []engine.Event{e})
+}
+
+// RecordBatch appends events to a scan in one transaction (one fsync).
+// Keys are the event timestamp plus a sequence number, so a cursor walks
+// the events in the order they happened. The scan's event count is set by
+// FinishScan.
+func (s *Store) RecordBatch(id string, events []engine.Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+	encoded := make([][]byte, len(events))
+	for i, e := range events {
+		data, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		encoded[i] = data
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(eventsBucket).Bucket([]byte(id))
+		if b == nil {
+			return ErrNotFound
+		}
+		for i, e := range events {
+			seq, err := b.NextSequence()
+			if err != nil {
+				return err
+			}
+			key := make([]byte, 16)
+			binary.BigEndian.PutUint64(key[:8], uint64(e.Time.UnixNano()))
+			binary.BigEndian.PutUint64(key[8:], seq)
+			if err := b.Put(key, encoded[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Scan returns the metadata of one scan
+func (s *Store) Scan(id string) (Scan, error) {
+	var scan Scan
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		scan, err = getScan(tx, id)
+		return err
+	})
+	return scan, err
+}
+
+// Scans lists every stored scan, newest first
+func (s *Store) Scans() ([]Scan, error) {
+	var scans []Scan
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(scansBucket).ForEach(func(k, v []byte) error {
+			var scan Scan
+			if err := json.Unmarshal(v, &scan); err != nil {
+				return fmt.Errorf("scan %s: %w", k, err)
+			}
+			scans = append(scans, scan)
+			return nil
+		})
+	})
+	sort.Slice(scans, func(i, j int) bool { return scans[i].Started.After(scans[j].Started) })
+	return scans, err
+}
+
+// PreviousScan returns the most recent completed scan of target that
+// started before the given scan
+func (s *Store) PreviousScan(target, beforeID string) (Scan, error) {
+	current, err := s.Scan(beforeID)
+	if err != nil {
+		return Scan{}, err
+	}
+	scans, err := s.Scans()
+	if err != nil {
+		return Scan{}, err
+	}
+	for _, scan := range scans { // newest first
+		if scan.Target == target && scan.Status == StatusComplete && scan.Started.Before(current.Started) {
+			return scan, nil
+		}
+	}
+	return Scan{}, ErrNotFound
+}
+
+// Events returns every event recorded for a scan, oldest first
+func (s *Store) Events(id string) ([]engine.Event, error) {
+	var events []engine.Event
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(eventsBucket).Bucket([]byte(id))
+		if b == nil {
+			return ErrNotFound
+		}
+		return b.ForEach(func(k, v []byte) error {
+			var e engine.Event
+			if err := json.Unmarshal(v, &e); err != nil {
+				return err
+			}
+			events = append(events, e)
+			return nil
+		})
+	})
+	return events, err
+}
+
+func getScan(tx *bolt.Tx, id string) (Scan, error) {
+	var scan Scan
+	data := tx.Bucket(scansBucket).Get([]byte(id))
+	if data == nil {
+		return scan, ErrNotFound
+	}
+	err := json.Unmarshal(data, &scan)
+	return scan, err
+}
+
+func putScan(tx *bolt.Tx, scan Scan) error {
+	data, err := json.Marshal(scan)
+	if err != nil {
+		return err
+	}
+	return tx.Bucket(scansBucket).Put([]byte(scan.ID), data)
+}
+
+// newScanID is sortable by time and short enough to type: 20240131-153000-a1b2
+func newScanID(t time.Time) string {
+	suffix := make([]byte, 2)
+	rand.Read(suffix)
+	return t.Format("20060102-150405") + "-" + hex.EncodeToString(suffix)
+}
@@ -0,0 +1,178 @@
+// Package targets turns scan input (domains, IP addresses, CIDR blocks and
+// IP ranges, from flags, files or stdin) into the hosts to scan.
+package targets
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"strings"
+)
+
+// MaxExpand caps how many addresses one CIDR block or range may expand to,
+// so a typo like 10.0.0.0/8 doesn't queue 16 million hosts
+const MaxExpand = 65536
+
+// List is the parsed input: names go through subdomain discovery, raw
+// addresses are scanned as they are
+type List struct {
+	Domains []string
+	IPs     []string
+}
+
+// Len is the number of entries in the list
+func (l List) Len() int { return len(l.Domains) + len(l.IPs) }
+
+// Parse sorts specs into domains and addresses, expanding CIDR blocks
+// ("10.0.0.0/24") and ranges ("10.0.0.1-10.0.0.50" or "10.0.0.1-50").
+// Duplicates are dropped, the order is kept.
+func Parse(specs []string) (List, error) {
+	var l List
+	seen := make(map[string]bool)
+	add := func(list *[]string, s string) {
+		if !seen[s] {
+			seen[s] = true
+			*list = append(*list, s)
+		}
+	}
+	for _, spec := range specs {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+		if IsAddress(spec) {
+			ips, err := Expand(spec)
+			if err != nil {
+				return List{}, err
+			}
+			for _, ip := range ips {
+				add(&l.IPs, ip)
+			}
+			continue
+		}
+		name := strings.ToLower(strings.TrimSuffix(spec, "."))
+		if strings.ContainsAny(name, "/ :") {
+			return List{}, fmt.Errorf("%q is neither a domain, an IP address, a CIDR block nor a range", spec)
+		}
+		add(&l.Domains, name)
+	}
+	return l, nil
+}
+
+// IsAddress reports whether spec is an IP address, CIDR block or IP range
+// rather than a name
+func IsAddress(spec string) bool {
+	first, _, _ := strings.Cut(spec, "/")
+	first, _, _ = strings.Cut(first, "-")
+	_, err := netip.ParseAddr(first)
+	return err == nil
+}
+
+// Expand returns every address of an IP, a CIDR block or a range
+func Expand(spec string) ([]string, error) {
+	from, to, err := Bounds(spec)
+	if err != nil {
+		return nil, err
+	}
+	var out []string
+	for ip := from; ip.IsValid() && !to.Less(ip); ip = ip.Next() {
+		if len(out) == MaxExpand {
+			return nil, fmt.Errorf("%s has more than %d addresses, split it up", spec, MaxExpand)
+		}
+		out = append(out, ip.Unmap().String())
+	}
+	return out, nil
+}
+
+// Bounds returns the first and last address of an IP, a CIDR block or a
+// range, without expanding it
+func Bounds(spec string) (from, to netip.Addr, err error) {
+	switch {
+	case strings.Contains(spec, "/"):
+		prefix, err := netip.ParsePrefix(spec)
+		if err != nil {
+			return from, to, fmt.Errorf("bad CIDR block %q: %w", spec, err)
+		}
+		prefix = prefix.Masked()
+		return prefix.Addr().Unmap(), lastAddr(prefix).Unmap(), nil
+	case strings.Contains(spec, "-"):
+		start, end, _ := strings.Cut(spec, "-")
+		if from, err = netip.ParseAddr(start); err != nil {
+			return from, to, fmt.Errorf("bad range %q: %w", spec, err)
+		}
+		from = from.Unmap()
+		if to, err = rangeEnd(from, end); err != nil {
+			return from, to, fmt.Errorf("bad range %q: %w", spec, err)
+		}
+		if to.Less(from) {
+			return from, to, fmt.Errorf("bad range %q: end is before start", spec)
+		}
+		return from, to, nil
+	default:
+		ip, err := netip.ParseAddr(spec)
+		if err != nil {
+			return from, to, err
+		}
+		return ip.Unmap(), ip.Unmap(), nil
+	}
+}
+
+// rangeEnd parses the end of a range: a full address, or just the last
+// IPv4 octet ("10.0.0.1-50")
+func rangeEnd(from netip.Addr, end string) (netip.Addr, error) {
+	if ip, err := netip.ParseAddr(end); err == nil {
+		ip = ip.Unmap()
+		if ip.Is4() != from.Is4() {
+			return netip.Addr{}, fmt.Errorf("start and end are of different families")
+		}
+		return ip, nil
+	}
+	if !from.Is4() {
+		return netip.Addr{}, fmt.Errorf("%q is not an address", end)
+	}
+	var octet int
+	if _, err := fmt.Sscanf(end, "%d", &octet); err != nil || octet < 0 || octet > 255 || fmt.Sprint(octet) != end {
+		return netip.Addr{}, fmt.Errorf("%q is neither an address nor an octet", end)
+	}
+	b := from.As4()
+	b[3] = byte(octet)
+	return netip.AddrFrom4(b), nil
+}
+
+func lastAddr(prefix netip.Prefix) netip.Addr {
+	b := prefix.Addr().AsSlice()
+	for i := prefix.Bits(); i < len(b)*8; i++ {
+		b[i/8] |= 1 << (7 - i%8)
+	}
+	ip, _ := netip.AddrFromSlice(b)
+	return ip
+}
+
+// Read returns the entries of a target list: one per line (or several
+// separated by commas or spaces), '#' starts a comment
+func Read(r io.Reader) ([]string, error) {
+	var specs []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		specs = append(specs, strings.FieldsFunc(line, func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\t'
+		})...)
+	}
+	return specs, scanner.Err()
+}
+
+// Load reads a target list from a file, or from stdin when filename is "-"
+func Load(filename string) ([]string, error) {
+	if filename == "-" {
+		return Read(os.Stdin)
+	}
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Read(f)
+}